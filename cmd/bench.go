@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// runBench times the simulation and drawing of the default world and prints how long each took and how much they
+// allocated.
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	seed := flags.Int64("seed", 1, "seed for the simulation's random source, keep it fixed to compare runs")
+	ticks := flags.Int("ticks", 100000, "number of ticks to time")
+	lists := flags.Int("lists", 100000, "number of times to list the board's entities")
+	frames := flags.Int("frames", 1000, "number of frames to draw")
+	scale := flags.Int("scale", 1, "pixels per cell when drawing frames")
+	start := addWorldFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ticks <= 0 || *lists <= 0 || *frames <= 0 || *scale <= 0 {
+		return fmt.Errorf("ticks, lists, frames and scale must be positive")
+	}
+
+	sim, err := newSimulator(*seed, "", start)
+	if err != nil {
+		return err
+	}
+	g := sim.Gameboard()
+
+	measure("tick", *ticks, func() {
+		sim.Step()
+	})
+
+	var entities []game.Entity
+	measure("list entities", *lists, func() {
+		entities = g.AppendEntities(entities[:0])
+	})
+	fmt.Printf("%d entities on the board\n", len(entities))
+
+	width, height := g.Size()
+	renderer := game.NewImageRenderer(width, height, *scale)
+	measure("draw frame", *frames, func() {
+		renderer.Clear()
+		game.DrawBoard(renderer, g)
+	})
+	return nil
+}
+
+// measure calls fn n times and prints the average time and allocations per call
+func measure(name string, n int, fn func()) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		fn()
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	fmt.Printf("%-14s %10d runs %12.0f ns/op %10.2f allocs/op %10.0f B/op\n", name, n,
+		float64(elapsed.Nanoseconds())/float64(n),
+		float64(after.Mallocs-before.Mallocs)/float64(n),
+		float64(after.TotalAlloc-before.TotalAlloc)/float64(n))
+}
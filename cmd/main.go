@@ -1,41 +1,328 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/hajimehoshi/ebiten"
 	"github.com/tannerhat/Cactus-Simulator/game"
 	"github.com/tannerhat/Cactus-Simulator/nature"
 )
 
-const (
-	screenWidth  = 600
-	screenHeight = 350
-	scale        = 5
-)
+// world is anything the initial entities can be added to
+type world interface {
+	AddEntity(entity game.Entity)
+}
 
 func main() {
-	ebiten.SetRunnableOnUnfocused(true)
-	ebiten.SetWindowSize(screenWidth*2, screenHeight*2)
-	ebiten.SetWindowTitle("Cactus Simulator")
-
-	game := game.NewGame(screenWidth, screenHeight, scale)
-
-	boardHeight := screenHeight / scale
-	boardWidth := screenWidth / scale
-	//cloudWidth := boardWidth / 8
-
-	game.AddEntity(nature.NewWeather(1000))
-	//game.AddEntity(nature.NewCloud((boardWidth/4)/2-(cloudWidth/2)+0*boardWidth/4, boardHeight/15+rand.Intn(boardHeight/15), cloudWidth, 2*cloudWidth/3, 1))
-	//game.AddEntity(nature.NewCloud((boardWidth/4)/2-(cloudWidth/2)+1*boardWidth/4, boardHeight/15+rand.Intn(boardHeight/15), cloudWidth, 2*cloudWidth/3, 1))
-	//game.AddEntity(nature.NewCloud((boardWidth/4)/2-(cloudWidth/2)+2*boardWidth/4, boardHeight/15+rand.Intn(boardHeight/15), cloudWidth, 2*cloudWidth/3, 1))
-	//game.AddEntity(nature.NewCloud((boardWidth/4)/2-(cloudWidth/2)+3*boardWidth/4, boardHeight/15+rand.Intn(boardHeight/15), cloudWidth, 2*cloudWidth/3, 1))
-	game.AddEntity(nature.NewSoil(0, boardHeight-3*boardHeight/6, boardWidth, 3*boardHeight/6))
-	r := nature.NewRoots(0, boardHeight-3*boardHeight/6, boardWidth, 3*boardHeight/6, boardWidth/2, 0)
-	game.AddEntity(r)
-	game.AddEntity(nature.NewPlant(boardWidth/2, boardHeight-3*boardHeight/6-1, r))
-
-	if err := ebiten.RunGame(game); err != nil {
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "run" {
+		err = runHeadless(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "term" {
+		err = runTerminal(os.Args[2:])
+	} else if len(os.Args) > 1 && os.Args[1] == "bench" {
+		err = runBench(os.Args[2:])
+	} else {
+		err = runWindow(os.Args[1:])
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// populate adds the scenario's entities to w, balanced by the settings in the config file at configPath or the
+// defaults if it is empty
+func populate(w world, scenario *nature.Scenario, configPath string) error {
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	entities, err := scenario.Build(config)
+	if err != nil {
+		return err
+	}
+	for _, e := range entities {
+		w.AddEntity(e)
+	}
+	return nil
+}
+
+// worldFlags are the flags that describe the world new games start from
+type worldFlags struct {
+	scenario *string
+	legend   *string
+	config   *string
+}
+
+func addWorldFlags(flags *flag.FlagSet) worldFlags {
+	return worldFlags{
+		scenario: flags.String("scenario", "", "JSON scenario file, or PNG map, describing the board and the entities new worlds start with"),
+		legend:   flags.String("legend", "", "JSON file of what each color of a PNG map is, the colors the game draws with by default"),
+		config:   flags.String("config", "", "JSON file of simulation settings for new worlds, settings it leaves out keep their defaults"),
+	}
+}
+
+// loadScenario reads the scenario given by the flags, or returns the default scenario if there isn't one. Maps take
+// their scale from the default scenario.
+func loadScenario(start worldFlags) (*nature.Scenario, error) {
+	path := *start.scenario
+	if path == "" {
+		return nature.DefaultScenario(), nil
+	}
+	var scenario *nature.Scenario
+	if strings.EqualFold(filepath.Ext(path), ".png") {
+		legend := nature.DefaultLegend()
+		if *start.legend != "" {
+			err := readFile(*start.legend, func(r io.Reader) error {
+				var err error
+				legend, err = nature.LoadLegend(r)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		err := readFile(path, func(r io.Reader) error {
+			img, err := png.Decode(r)
+			if err != nil {
+				return err
+			}
+			scenario, err = nature.ScenarioFromMap(img, legend, nature.DefaultScenario().Scale)
+			return err
+		})
+		return scenario, err
+	}
+
+	err := readFile(path, func(r io.Reader) error {
+		var err error
+		scenario, err = nature.LoadScenario(r)
+		return err
+	})
+	return scenario, err
+}
+
+// loadConfig reads the simulation settings at path, or returns the defaults if path is empty
+func loadConfig(path string) (nature.Config, error) {
+	if path == "" {
+		return nature.DefaultConfig(), nil
+	}
+	var config nature.Config
+	err := readFile(path, func(r io.Reader) error {
+		var err error
+		config, err = nature.LoadConfig(r)
+		return err
+	})
+	return config, err
+}
+
+// newSimulator loads the snapshot at path, or creates a new world from seed and the world flags if path is empty
+func newSimulator(seed int64, path string, start worldFlags) (*game.Simulator, error) {
+	if path == "" {
+		scenario, err := loadScenario(start)
+		if err != nil {
+			return nil, err
+		}
+		sim := game.NewSimulator(scenario.Width, scenario.Height, seed)
+		if err := populate(sim, scenario, *start.config); err != nil {
+			return nil, err
+		}
+		return sim, nil
+	}
+
+	var sim *game.Simulator
+	err := readFile(path, func(r io.Reader) error {
+		var err error
+		sim, err = game.LoadSimulator(r)
+		return err
+	})
+	return sim, err
+}
+
+// runHeadless runs the simulation without a window for a fixed number of ticks and prints the final state.
+func runHeadless(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	ticks := flags.Int("ticks", 60*60*60, "number of ticks to simulate")
+	untilWin := flags.Bool("until-win", false, "stop as soon as the plant wins")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed for the simulation's random source")
+	load := flags.String("load", "", "snapshot file to start from instead of a new world")
+	start := addWorldFlags(flags)
+	save := flags.String("save", "", "file to write a snapshot of the final state to")
+	replay := flags.String("replay", "", "replay file to re-simulate, its recorded actions are applied on their ticks")
+	events := flags.Bool("events", false, "print events as they happen, other than cells changing")
+	export := flags.String("export", "", "PNG file to write the final board to, one pixel per cell")
+	timelapse := flags.String("timelapse", "", "file ending in .gif or directory of PNGs to write a timelapse to")
+	every := flags.Int("every", 60*60, "number of ticks between timelapse frames")
+	shrink := flags.Int("shrink", 1, "number of cells across each timelapse pixel averages")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *ticks < 0 {
+		return fmt.Errorf("ticks must not be negative, got %d", *ticks)
+	}
+
+	var sim *game.Simulator
+	step := func() (bool, error) { return sim.Step(), nil }
+	if *replay != "" {
+		err := readFile(*replay, func(r io.Reader) error {
+			replay, err := game.LoadReplay(r)
+			if err != nil {
+				return err
+			}
+			player, err := replay.Play()
+			if err != nil {
+				return err
+			}
+			sim = player.Simulator()
+			step = player.Step
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		var err error
+		sim, err = newSimulator(*seed, *load, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	if *events {
+		sim.Gameboard().Subscribe(func(e game.Event) {
+			printEvent(sim.Ticks(), e)
+		})
+	}
+
+	var recorder *game.Timelapse
+	if *timelapse != "" {
+		out, err := game.CreateTimelapseOutput(*timelapse)
+		if err != nil {
+			return err
+		}
+		width, height := sim.Gameboard().Size()
+		recorder, err = game.NewTimelapse(width, height, *every, *shrink, out)
+		if err != nil {
+			out.Close()
+			return err
+		}
+	}
+
+	ran := 0
+	for ran < *ticks {
+		ran++
+		won, err := step()
+		if err != nil {
+			if recorder != nil {
+				recorder.Close()
+			}
+			return err
+		}
+		if recorder != nil {
+			if err := recorder.Capture(sim); err != nil {
+				recorder.Close()
+				return err
+			}
+		}
+		if won && *untilWin {
+			break
+		}
+	}
+	printState(sim, ran)
+
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			return err
+		}
+		fmt.Printf("wrote %d timelapse frames to %s\n", recorder.Frames(), *timelapse)
+	}
+
+	if *export != "" {
+		err := writeFile(*export, func(w io.Writer) error {
+			return png.Encode(w, game.BoardImage(sim.Gameboard()))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *save != "" {
+		return writeFile(*save, sim.Save)
+	}
+	return nil
+}
+
+// printEvent writes a line describing e to stdout. Cell changes and moves are skipped, there are far too many of them
+// to be useful in a log.
+func printEvent(tick int, e game.Event) {
+	switch ev := e.(type) {
+	case game.CellChanged, game.EntityMoved:
+		return
+	case game.EntityAdded:
+		fmt.Printf("tick %d: added %T #%d\n", tick, ev.Entity, ev.ID)
+	case game.EntityRemoved:
+		fmt.Printf("tick %d: removed %T #%d\n", tick, ev.Entity, ev.ID)
+	case nature.WaterAbsorbed:
+		fmt.Printf("tick %d: %T absorbed water at (%d,%d)\n", tick, ev.Absorber, ev.X, ev.Y)
+	case nature.RootGrown:
+		fmt.Printf("tick %d: root grew at (%d,%d)\n", tick, ev.X, ev.Y)
+	case nature.PlantGrew:
+		fmt.Printf("tick %d: plant grew to %dx%d\n", tick, ev.Width, ev.Height)
+	default:
+		fmt.Printf("tick %d: %T\n", tick, e)
+	}
+}
+
+// readFile opens the file at path and passes it to read
+func readFile(path string, read func(io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return read(f)
+}
+
+// writeFile creates the file at path and passes it to write
+func writeFile(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printState writes a summary of the simulation to stdout
+func printState(sim *game.Simulator, ran int) {
+	fmt.Printf("ran %d ticks (%0.2f game hours) with seed %d, won: %t\n", ran, sim.Hours(), sim.Seed(), sim.Won())
+
+	counts := map[string]int{}
+	summaries := []string{}
+	for _, e := range sim.Gameboard().AppendEntities(nil) {
+		counts[fmt.Sprintf("%T", e)]++
+		if s, ok := e.(fmt.Stringer); ok {
+			summaries = append(summaries, s.String())
+		}
+	}
+
+	types := make([]string, 0, len(counts))
+	for t := range counts {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		fmt.Printf("%6d %s\n", counts[t], t)
+	}
+	for _, s := range summaries {
+		fmt.Println(s)
+	}
+}
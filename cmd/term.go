@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// termKeys are the keys the terminal viewer reacts to, they match the window's default bindings
+var termKeys = map[byte]game.Action{
+	'`': game.ActionPause,
+	'~': game.ActionPause,
+	'1': game.ActionSpeed1,
+	'2': game.ActionSpeed2,
+	'3': game.ActionSpeed3,
+	'4': game.ActionSpeed4,
+	'=': game.ActionSpeedUp,
+	'-': game.ActionSpeedDown,
+	' ': game.ActionAbsorb,
+}
+
+// maxFPS is the fastest the terminal viewer redraws, far more than a terminal can show
+const maxFPS = 1000
+
+// runTerminal shows the simulation in the terminal using ANSI colors, for watching a run without a window.
+func runTerminal(args []string) error {
+	flags := flag.NewFlagSet("term", flag.ContinueOnError)
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed for the simulation's random source")
+	load := flags.String("load", "", "snapshot file to start from instead of a new world")
+	start := addWorldFlags(flags)
+	fps := flags.Int("fps", 10, "number of times a second the terminal is redrawn")
+	speed := flags.Int("speed", 1, "number of ticks simulated per redraw")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *fps < 1 || *fps > maxFPS {
+		return fmt.Errorf("fps must be between 1 and %d, got %d", maxFPS, *fps)
+	}
+	if *speed < 0 {
+		return fmt.Errorf("speed must not be negative, got %d", *speed)
+	}
+
+	sim, err := newSimulator(*seed, *load, start)
+	if err != nil {
+		return err
+	}
+	width, height := sim.Gameboard().Size()
+	renderer := game.NewImageRenderer(width, height, 1)
+
+	restore, err := rawTerminal()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	keys := make(chan byte)
+	go func() {
+		in := bufio.NewReader(os.Stdin)
+		for {
+			b, err := in.ReadByte()
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- b
+		}
+	}()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	out := bufio.NewWriter(os.Stdout)
+	// clear the screen and hide the cursor, it is shown again when the viewer exits
+	fmt.Fprint(out, "\x1b[2J\x1b[?25l")
+	defer func() {
+		fmt.Fprint(out, "\x1b[0m\x1b[?25h\n")
+		out.Flush()
+	}()
+
+	ticker := time.NewTicker(time.Second / time.Duration(*fps))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupt:
+			return nil
+		case key, ok := <-keys:
+			if !ok || key == 'q' {
+				return nil
+			}
+			action, ok := termKeys[key]
+			if !ok {
+				continue
+			}
+			if s, ok := game.ApplySpeed(action, *speed); ok {
+				*speed = s
+			} else if action.Simulated() {
+				sim.Trigger(action)
+			}
+		case <-ticker.C:
+			for i := 0; i < *speed; i++ {
+				if sim.Step() {
+					// stop once the plant wins like the window does
+					*speed = 0
+				}
+			}
+
+			renderer.Clear()
+			game.DrawBoard(renderer, sim.Gameboard())
+			status := fmt.Sprintf("tick %d  %0.2f game hours  speed %d", sim.Ticks(), sim.Hours(), *speed)
+			if sim.Won() {
+				status += "  YOU GREW THE PERFECT CACTUS"
+			}
+			status += "  (~ pause, 1-4 speed, -/= slower/faster, space absorb, q quit)"
+			drawTerminal(out, renderer.Image(), status)
+			if err := out.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// drawTerminal writes img to w with each character showing two rows of pixels, the top one as the foreground of a
+// half block and the bottom one as the background. status is written on the line below.
+func drawTerminal(w io.Writer, img *image.RGBA, status string) {
+	bounds := img.Bounds()
+	var b strings.Builder
+	// start from the top left corner so each frame overwrites the last
+	b.WriteString("\x1b[H")
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 2 {
+		var fg, bg color.RGBA
+		first := true
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			top := img.RGBAAt(x, y)
+			bottom := color.RGBA{}
+			if y+1 < bounds.Max.Y {
+				bottom = img.RGBAAt(x, y+1)
+			}
+			// colors are only written when they change, most neighbouring cells are the same
+			if first || top != fg {
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm", top.R, top.G, top.B)
+				fg = top
+			}
+			if first || bottom != bg {
+				fmt.Fprintf(&b, "\x1b[48;2;%d;%d;%dm", bottom.R, bottom.G, bottom.B)
+				bg = bottom
+			}
+			first = false
+			b.WriteString("▀")
+		}
+		b.WriteString("\x1b[0m\n")
+	}
+	b.WriteString(status)
+	// clear whatever is left of a longer status from the last frame
+	b.WriteString("\x1b[K")
+	io.WriteString(w, b.String())
+}
+
+// rawTerminal makes the terminal hand over key presses as they happen without echoing them. The returned function
+// puts the terminal back the way it was.
+func rawTerminal() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("can't change the terminal mode, is stdin a terminal? %v", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, fmt.Errorf("can't change the terminal mode: %v", err)
+	}
+	return func() {
+		stty(strings.TrimSpace(saved))
+	}, nil
+}
+
+// stty runs stty against the terminal on stdin and returns what it printed
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
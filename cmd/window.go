@@ -0,0 +1,111 @@
+//go:build !headless
+// +build !headless
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"github.com/tannerhat/Cactus-Simulator/nature"
+	"github.com/tannerhat/Cactus-Simulator/window"
+)
+
+// runWindow opens the game window and runs until it is closed.
+func runWindow(args []string) error {
+	flags := flag.NewFlagSet("cactus", flag.ContinueOnError)
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed for the simulation's random source")
+	load := flags.String("load", "", "snapshot file to start from instead of a new world")
+	start := addWorldFlags(flags)
+	record := flags.String("record", "", "file to write a replay of the session to when the game exits")
+	replay := flags.String("replay", "", "replay file to watch, the keyboard takes over when it runs out")
+	every := flags.Int("every", 60*60, "number of ticks between frames of timelapses started with the timelapse key")
+	shrink := flags.Int("shrink", 1, "number of cells across each timelapse pixel averages")
+	keys := flags.String("keys", "", "JSON file of key bindings, actions it leaves out keep their default keys")
+	edit := flags.String("edit", "", "scenario file the level editor saves to, the game starts in the editor with the scenario")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	scene, err := loadScenario(start)
+	if err != nil {
+		return err
+	}
+	screenWidth, screenHeight := scene.Width*scene.Scale, scene.Height*scene.Scale
+
+	ebiten.SetRunnableOnUnfocused(true)
+	ebiten.SetWindowSize(screenWidth*2, screenHeight*2)
+	ebiten.SetWindowTitle("Cactus Simulator")
+
+	g := window.NewGame(screenWidth, screenHeight, scene.Scale, *seed)
+	g.SetTimelapse(*every, *shrink)
+	if *keys != "" {
+		err := readFile(*keys, func(r io.Reader) error {
+			bindings, err := window.LoadBindings(r)
+			if err != nil {
+				return err
+			}
+			g.SetBindings(bindings)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *load != "" {
+		if err := readFile(*load, g.Load); err != nil {
+			return err
+		}
+	} else if err := populate(g, scene, *start.config); err != nil {
+		return err
+	}
+
+	if *edit != "" {
+		config, err := loadConfig(*start.config)
+		if err != nil {
+			return err
+		}
+		layout, err := nature.LayoutFromScenario(scene, config)
+		if err != nil {
+			return fmt.Errorf("editing scenario: %v", err)
+		}
+		g.SetEditor(nature.NewEditor(layout, config), *edit)
+		g.Edit()
+	}
+
+	if *replay != "" {
+		err := readFile(*replay, func(r io.Reader) error {
+			replay, err := game.LoadReplay(r)
+			if err != nil {
+				return err
+			}
+			return g.Play(replay)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *record != "" {
+		if err := g.Record(); err != nil {
+			return err
+		}
+	}
+
+	runErr := ebiten.RunGame(g)
+	g.Close()
+	if err := g.StopTimelapse(); err != nil {
+		log.Printf("writing timelapse: %v", err)
+	}
+	if *record != "" {
+		if err := writeFile(*record, g.Recording().Save); err != nil {
+			log.Printf("writing replay: %v", err)
+		}
+	}
+	return runErr
+}
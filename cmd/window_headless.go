@@ -0,0 +1,14 @@
+//go:build headless
+// +build headless
+
+package main
+
+import (
+	"fmt"
+)
+
+// runWindow fails because headless builds leave out ebiten, this build has no window. The run, term and bench
+// subcommands don't need one.
+func runWindow(args []string) error {
+	return fmt.Errorf("this build has no window, use the run, term or bench subcommand")
+}
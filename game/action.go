@@ -0,0 +1,139 @@
+package game
+
+import (
+	"fmt"
+)
+
+// Action is something the player asked the game to do. Key presses are turned into actions before they are acted
+// on so that a session's actions can be recorded and replayed.
+type Action int
+
+const (
+	ActionPause Action = iota
+	ActionSpeed1
+	ActionSpeed2
+	ActionSpeed3
+	ActionSpeed4
+	ActionSpeedUp
+	ActionSpeedDown
+	ActionDebug
+	ActionAbsorb
+	ActionQuickSave
+	ActionQuickLoad
+	ActionTimelapse
+	ActionScreenshot
+	ActionStart
+	ActionQuit
+	ActionEdit
+	ActionNextTool
+	ActionPrevTool
+	ActionSaveLevel
+	// ActionUseTool is a use of one of the tools with the mouse, it isn't bound to a key
+	ActionUseTool
+	// actionCount is the number of actions, it must stay last
+	actionCount
+)
+
+var actionNames = map[Action]string{
+	ActionPause:      "pause",
+	ActionSpeed1:     "speed1",
+	ActionSpeed2:     "speed2",
+	ActionSpeed3:     "speed3",
+	ActionSpeed4:     "speed4",
+	ActionSpeedUp:    "speedup",
+	ActionSpeedDown:  "speeddown",
+	ActionDebug:      "debug",
+	ActionAbsorb:     "absorb",
+	ActionQuickSave:  "quicksave",
+	ActionQuickLoad:  "quickload",
+	ActionTimelapse:  "timelapse",
+	ActionScreenshot: "screenshot",
+	ActionStart:      "start",
+	ActionQuit:       "quit",
+	ActionEdit:       "edit",
+	ActionNextTool:   "nexttool",
+	ActionPrevTool:   "prevtool",
+	ActionSaveLevel:  "savelevel",
+	ActionUseTool:    "usetool",
+}
+
+// actionDescriptions are shown next to an action's keys in the list of controls
+var actionDescriptions = map[Action]string{
+	ActionPause:      "pause",
+	ActionSpeed1:     "1x speed",
+	ActionSpeed2:     "10x speed",
+	ActionSpeed3:     "60x speed",
+	ActionSpeed4:     "300x speed",
+	ActionSpeedUp:    "speed up",
+	ActionSpeedDown:  "slow down",
+	ActionDebug:      "debug info",
+	ActionAbsorb:     "absorb water",
+	ActionQuickSave:  "quick save",
+	ActionQuickLoad:  "quick load",
+	ActionTimelapse:  "record timelapse",
+	ActionScreenshot: "screenshot",
+	ActionStart:      "start",
+	ActionQuit:       "leave",
+	ActionEdit:       "edit level",
+	ActionNextTool:   "next tool",
+	ActionPrevTool:   "previous tool",
+	ActionSaveLevel:  "save level",
+	ActionUseTool:    "use tool",
+}
+
+// Actions returns every action in order.
+func Actions() []Action {
+	actions := make([]Action, actionCount)
+	for i := range actions {
+		actions[i] = Action(i)
+	}
+	return actions
+}
+
+// Simulated returns true if the action is seen by the entities on the board. Other actions only change how the game
+// is shown.
+func (a Action) Simulated() bool {
+	return a == ActionAbsorb
+}
+
+// Recorded returns true if the action belongs in a replay. Saving, loading, capturing the screen, editing the level and
+// leaving the game control the session itself so they aren't recorded.
+func (a Action) Recorded() bool {
+	switch a {
+	case ActionQuickSave, ActionQuickLoad, ActionTimelapse, ActionScreenshot, ActionStart, ActionQuit, ActionEdit,
+		ActionNextTool, ActionPrevTool, ActionSaveLevel:
+		return false
+	}
+	return true
+}
+
+// Description returns a short explanation of what the action does.
+func (a Action) Description() string {
+	return actionDescriptions[a]
+}
+
+func (a Action) String() string {
+	if name, ok := actionNames[a]; ok {
+		return name
+	}
+	return fmt.Sprintf("Action(%d)", int(a))
+}
+
+// MarshalText stores actions by name so recordings stay readable and don't depend on the order of the constants.
+func (a Action) MarshalText() ([]byte, error) {
+	name, ok := actionNames[a]
+	if !ok {
+		return nil, fmt.Errorf("unknown action %d", int(a))
+	}
+	return []byte(name), nil
+}
+
+func (a *Action) UnmarshalText(text []byte) error {
+	for action, name := range actionNames {
+		if name == string(text) {
+			*a = action
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown action %q", string(text))
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrOutOfBounds is returned by the checked Gameboard accessors when given a location off the board.
+var ErrOutOfBounds = errors.New("location is off the board")
+
+// Edge is one of the four sides of the board.
+type Edge int
+
+const (
+	EdgeLeft Edge = iota
+	EdgeRight
+	EdgeTop
+	EdgeBottom
+	// edgeCount is the number of edges, it must stay last
+	edgeCount
+)
+
+var edgeNames = map[Edge]string{
+	EdgeLeft:   "left",
+	EdgeRight:  "right",
+	EdgeTop:    "top",
+	EdgeBottom: "bottom",
+}
+
+func (e Edge) String() string {
+	if name, ok := edgeNames[e]; ok {
+		return name
+	}
+	return fmt.Sprintf("Edge(%d)", int(e))
+}
+
+// Boundary is what happens to an entity that moves across an edge of the board.
+type Boundary int
+
+const (
+	// NoBoundary is returned by Locate for locations on the board
+	NoBoundary Boundary = iota
+	// Wall stops entities at the edge
+	Wall
+	// Drain lets entities leave the board, what that means is up to the entity, water is lost
+	Drain
+	// Wrap moves entities to the opposite edge
+	Wrap
+)
+
+var boundaryNames = map[Boundary]string{
+	NoBoundary: "none",
+	Wall:       "wall",
+	Drain:      "drain",
+	Wrap:       "wrap",
+}
+
+// defaultBoundaries match how the board always behaved, water drains off the sides and the top and bottom are walls
+var defaultBoundaries = [edgeCount]Boundary{
+	EdgeLeft:   Drain,
+	EdgeRight:  Drain,
+	EdgeTop:    Wall,
+	EdgeBottom: Wall,
+}
+
+func (b Boundary) String() string {
+	if name, ok := boundaryNames[b]; ok {
+		return name
+	}
+	return fmt.Sprintf("Boundary(%d)", int(b))
+}
+
+// MarshalText stores boundaries by name so snapshots and config files stay readable.
+func (b Boundary) MarshalText() ([]byte, error) {
+	name, ok := boundaryNames[b]
+	if !ok {
+		return nil, fmt.Errorf("unknown boundary %d", int(b))
+	}
+	return []byte(name), nil
+}
+
+func (b *Boundary) UnmarshalText(text []byte) error {
+	for boundary, name := range boundaryNames {
+		if name == string(text) {
+			*b = boundary
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown boundary %q", string(text))
+}
+
+func outOfBounds(x int, y int) error {
+	return fmt.Errorf("(%d,%d): %w", x, y, ErrOutOfBounds)
+}
+
+// wrap returns v moved into [0,size) as if both ends of the range were joined
+func wrap(v int, size int) int {
+	v %= size
+	if v < 0 {
+		v += size
+	}
+	return v
+}
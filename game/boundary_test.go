@@ -0,0 +1,74 @@
+package game
+
+import (
+	"testing"
+)
+
+func TestLocate(t *testing.T) {
+	// the board is 5x4, so its corners are (0,0), (4,0), (0,3) and (4,3)
+	type location struct{ x, y int }
+	onBoard := []location{{0, 0}, {4, 0}, {0, 3}, {4, 3}, {2, 1}}
+	offBoard := []struct {
+		at      location
+		wrapped location
+	}{
+		{location{-1, 2}, location{4, 2}},
+		{location{5, 2}, location{0, 2}},
+		{location{2, -1}, location{2, 3}},
+		{location{2, 4}, location{2, 0}},
+		{location{-1, -1}, location{4, 3}},
+		{location{5, -1}, location{0, 3}},
+		{location{-1, 4}, location{4, 0}},
+		{location{5, 4}, location{0, 0}},
+		{location{-6, 2}, location{4, 2}},
+		{location{11, 9}, location{1, 1}},
+	}
+
+	for _, boundary := range []Boundary{Wrap, Drain, Wall} {
+		t.Run(boundary.String(), func(t *testing.T) {
+			g := newGameboard(5, 4, 1)
+			for edge := Edge(0); edge < edgeCount; edge++ {
+				g.SetBoundary(edge, boundary)
+			}
+			for _, l := range onBoard {
+				if x, y, b := g.Locate(l.x, l.y); x != l.x || y != l.y || b != NoBoundary {
+					t.Errorf("Locate(%d,%d) = (%d,%d) %v, want it unchanged with no boundary", l.x, l.y, x, y, b)
+				}
+			}
+			for _, c := range offBoard {
+				want, wantBoundary := c.at, boundary
+				if boundary == Wrap {
+					want, wantBoundary = c.wrapped, NoBoundary
+				}
+				if x, y, b := g.Locate(c.at.x, c.at.y); x != want.x || y != want.y || b != wantBoundary {
+					t.Errorf("Locate(%d,%d) = (%d,%d) %v, want (%d,%d) %v", c.at.x, c.at.y, x, y, b, want.x, want.y,
+						wantBoundary)
+				}
+			}
+		})
+	}
+}
+
+func TestLocateCornerOfMixedEdges(t *testing.T) {
+	g := newGameboard(5, 4, 1)
+	g.SetBoundary(EdgeLeft, Wrap)
+	g.SetBoundary(EdgeRight, Drain)
+	g.SetBoundary(EdgeTop, Wall)
+	g.SetBoundary(EdgeBottom, Wrap)
+	for _, c := range []struct {
+		x, y, wantX, wantY int
+		want               Boundary
+	}{
+		// past the top left corner the location wraps across the left edge and is then stopped by the top
+		{-1, -1, 4, -1, Wall},
+		// past the bottom left corner it wraps across both
+		{-1, 4, 4, 0, NoBoundary},
+		// the right edge is crossed first, so the drain wins over the bottom's wrap
+		{5, 4, 5, 4, Drain},
+		{5, -1, 5, -1, Drain},
+	} {
+		if x, y, b := g.Locate(c.x, c.y); x != c.wantX || y != c.wantY || b != c.want {
+			t.Errorf("Locate(%d,%d) = (%d,%d) %v, want (%d,%d) %v", c.x, c.y, x, y, b, c.wantX, c.wantY, c.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"image"
+	"image/color"
+	"sync/atomic"
+)
+
+// CellBuffer is a picture of a block of gameboard cells with one pixel per cell. Entities keep one between frames and
+// only change the pixels of cells that change, so drawing them is a single call instead of one per cell. Renderers
+// may cache what they make from a buffer until its Version changes.
+type CellBuffer struct {
+	// X and Y are the gameboard coordinates of the buffer's top left cell
+	X int
+	Y int
+
+	image   *image.RGBA
+	version uint64
+}
+
+// NewCellBuffer returns a transparent buffer of width x height cells with its top left cell at gameboard (x,y).
+func NewCellBuffer(x int, y int, width int, height int) *CellBuffer {
+	return &CellBuffer{
+		X:     x,
+		Y:     y,
+		image: image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+}
+
+// Set colors the cell (x,y) of the buffer, counted from its top left cell. Different cells may be set from different
+// goroutines at once.
+func (b *CellBuffer) Set(x int, y int, c color.Color) {
+	b.SetRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+// SetRGBA is Set for a color that is already a color.RGBA, it is cheaper for cells that change every tick.
+func (b *CellBuffer) SetRGBA(x int, y int, c color.RGBA) {
+	if b.image.RGBAAt(x, y) == c {
+		return
+	}
+	b.image.SetRGBA(x, y, c)
+	atomic.AddUint64(&b.version, 1)
+}
+
+// Resize changes the buffer to width x height cells and makes every cell transparent.
+func (b *CellBuffer) Resize(width int, height int) {
+	if w, h := b.Size(); w != width || h != height {
+		b.image = image.NewRGBA(image.Rect(0, 0, width, height))
+	} else {
+		for i := range b.image.Pix {
+			b.image.Pix[i] = 0
+		}
+	}
+	atomic.AddUint64(&b.version, 1)
+}
+
+// Size returns the width and height of the buffer in cells.
+func (b *CellBuffer) Size() (int, int) {
+	size := b.image.Bounds().Size()
+	return size.X, size.Y
+}
+
+// Image returns the buffer's pixels, it must not be changed.
+func (b *CellBuffer) Image() *image.RGBA {
+	return b.image
+}
+
+// Version returns a number that changes whenever a pixel of the buffer does.
+func (b *CellBuffer) Version() uint64 {
+	return atomic.LoadUint64(&b.version)
+}
@@ -0,0 +1,37 @@
+package game
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestCellBufferVersion(t *testing.T) {
+	b := NewCellBuffer(0, 0, 4, 3)
+	red := color.RGBA{0xff, 0x00, 0x00, 0xff}
+	steps := []struct {
+		name    string
+		change  func()
+		changed bool
+	}{
+		{"set a transparent cell transparent", func() { b.Set(1, 1, color.Transparent) }, false},
+		{"set a cell red", func() { b.Set(1, 1, red) }, true},
+		{"set it red again", func() { b.SetRGBA(1, 1, red) }, false},
+		{"set it red as another color type", func() { b.Set(1, 1, color.NRGBA{0xff, 0x00, 0x00, 0xff}) }, false},
+		{"set another cell red", func() { b.SetRGBA(3, 2, red) }, true},
+		{"clear by resizing to the same size", func() { b.Resize(4, 3) }, true},
+		{"resize", func() { b.Resize(5, 5) }, true},
+	}
+	for _, step := range steps {
+		before := b.Version()
+		step.change()
+		if changed := b.Version() != before; changed != step.changed {
+			t.Errorf("%s: version changed is %t, want %t", step.name, changed, step.changed)
+		}
+	}
+	if w, h := b.Size(); w != 5 || h != 5 {
+		t.Errorf("buffer is %dx%d after resizing, want 5x5", w, h)
+	}
+	if c := b.Image().RGBAAt(3, 2); c != (color.RGBA{}) {
+		t.Errorf("resizing left the cell %v, want it transparent", c)
+	}
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"image"
+	"image/color"
+	"sync/atomic"
+)
+
+// DrawList is a Renderer that records what is drawn so it can be drawn again later through another Renderer. Cell
+// buffers are copied as they are drawn, so a list can be replayed on another goroutine while the board keeps changing.
+type DrawList struct {
+	scale int
+	ops   []drawOp
+	// buffers holds the copies of the cell buffers drawn into the list, they are reused by the next recording
+	buffers []*bufferCopy
+	used    int
+}
+
+type drawOpKind int
+
+const (
+	drawRect drawOpKind = iota
+	drawBuffer
+	drawText
+)
+
+type drawOp struct {
+	kind   drawOpKind
+	x      int
+	y      int
+	width  int
+	height int
+	color  color.RGBA
+	buffer *CellBuffer
+	text   string
+}
+
+// bufferCopy is a copy of a cell buffer along with where it came from, so it is only copied again once the source
+// changes
+type bufferCopy struct {
+	buffer  *CellBuffer
+	source  *CellBuffer
+	version uint64
+}
+
+// NewDrawList returns an empty list that reports scale as the renderer's scale.
+func NewDrawList(scale int) *DrawList {
+	return &DrawList{scale: scale}
+}
+
+// Reset empties the list so it can be recorded again.
+func (l *DrawList) Reset() {
+	for i := range l.ops {
+		l.ops[i] = drawOp{}
+	}
+	l.ops = l.ops[:0]
+	l.used = 0
+}
+
+// Draw draws everything recorded in the list through r, in the order it was recorded.
+func (l *DrawList) Draw(r Renderer) {
+	for _, op := range l.ops {
+		switch op.kind {
+		case drawRect:
+			r.FillRect(op.x, op.y, op.width, op.height, op.color)
+		case drawBuffer:
+			r.DrawBuffer(op.buffer)
+		case drawText:
+			r.DrawText(op.text, op.x, op.y, op.color)
+		}
+	}
+}
+
+func (l *DrawList) FillCell(x int, y int, c color.Color) {
+	l.FillRect(x, y, 1, 1, c)
+}
+
+func (l *DrawList) FillRect(x int, y int, width int, height int, c color.Color) {
+	l.ops = append(l.ops, drawOp{kind: drawRect, x: x, y: y, width: width, height: height, color: color.RGBAModel.Convert(c).(color.RGBA)})
+}
+
+func (l *DrawList) DrawBuffer(b *CellBuffer) {
+	if l.used == len(l.buffers) {
+		l.buffers = append(l.buffers, &bufferCopy{buffer: NewCellBuffer(0, 0, 0, 0)})
+	}
+	copied := l.buffers[l.used]
+	l.used++
+
+	version := b.Version()
+	if copied.source != b || copied.version != version {
+		copied.buffer.copyFrom(b)
+		copied.source = b
+		copied.version = version
+	}
+	copied.buffer.X = b.X
+	copied.buffer.Y = b.Y
+	l.ops = append(l.ops, drawOp{kind: drawBuffer, buffer: copied.buffer})
+}
+
+func (l *DrawList) DrawText(s string, x int, y int, c color.Color) {
+	l.ops = append(l.ops, drawOp{kind: drawText, x: x, y: y, color: color.RGBAModel.Convert(c).(color.RGBA), text: s})
+}
+
+func (l *DrawList) Scale() int {
+	return l.scale
+}
+
+// copyFrom makes b's pixels match src's. b gets a new version, not src's, since b's versions are only compared with
+// its own.
+func (b *CellBuffer) copyFrom(src *CellBuffer) {
+	width, height := src.Size()
+	if w, h := b.Size(); w != width || h != height {
+		b.image = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	copy(b.image.Pix, src.image.Pix)
+	atomic.AddUint64(&b.version, 1)
+}
@@ -1,13 +1,15 @@
 package game
 
-import (
-	"github.com/hajimehoshi/ebiten"
-)
+// EntityID identifies an entity on a Gameboard. IDs are handed out in order starting at 1 as entities are added.
+type EntityID uint64
+
+// NoEntity is the ID of nothing, it is used for empty references.
+const NoEntity EntityID = 0
 
 // Entity defines necessary functions for an entity that can be added to the game
 type Entity interface {
-	// Draw the entity to the given screen at the given scale
-	Draw(screen *ebiten.Image, scale int)
+	// Draw the entity through the given renderer
+	Draw(r Renderer)
 
 	// Update the entity by one tick
 	Update()
@@ -0,0 +1,69 @@
+package game
+
+// Event is something that happened on a Gameboard. Subscribers are sent every event and switch on the type of the
+// ones they care about. The game package publishes the board events below, other packages publish their own.
+type Event interface{}
+
+// EntityAdded is published after an entity is added to the board and has marked its positions.
+type EntityAdded struct {
+	Entity Entity
+	ID     EntityID
+}
+
+// EntityRemoved is published after an entity is taken out of the board's entity list.
+type EntityRemoved struct {
+	Entity Entity
+	ID     EntityID
+}
+
+// EntityMoved is published when MoveEntity moves an entity in Layer from (FromX,FromY) to (X,Y). Moves do not also
+// publish CellChanged for the two cells.
+type EntityMoved struct {
+	Entity Entity
+	Layer  CellLayer
+	FromX  int
+	FromY  int
+	X      int
+	Y      int
+}
+
+// CellChanged is published when SetEntity changes what is in Layer at (X,Y).
+type CellChanged struct {
+	X     int
+	Y     int
+	Layer CellLayer
+	Old   Entity
+	New   Entity
+}
+
+// subscription is a handler registered with Subscribe
+type subscription struct {
+	handler func(Event)
+}
+
+// events keeps track of subscribers and hands them published events
+type events struct {
+	subscriptions []*subscription
+}
+
+func (ev *events) Subscribe(handler func(Event)) func() {
+	s := &subscription{handler: handler}
+	ev.subscriptions = append(ev.subscriptions, s)
+	return func() {
+		for i, other := range ev.subscriptions {
+			if other == s {
+				// copy rather than shifting in place so a Publish that is ranging over the old slice isn't disturbed
+				subscriptions := make([]*subscription, 0, len(ev.subscriptions)-1)
+				subscriptions = append(subscriptions, ev.subscriptions[:i]...)
+				ev.subscriptions = append(subscriptions, ev.subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (ev *events) Publish(e Event) {
+	for _, s := range ev.subscriptions {
+		s.handler(e)
+	}
+}
@@ -1,95 +1,478 @@
 package game
 
 import (
-	"sync"
+	"math/rand"
 )
 
+// CellLayer is one of the layers a game location is split into. Each layer of a location holds at most one entity, so
+// entities that share space, like roots growing through soil or water pooled on rock, can all be on the board.
+type CellLayer int
+
+const (
+	// GroundLayer holds whatever fills the location, like soil, rock or the plant's body
+	GroundLayer CellLayer = iota
+	// UndergroundLayer holds things living inside the ground, like roots
+	UndergroundLayer
+	// FluidLayer holds water
+	FluidLayer
+	// AirLayer holds things floating in the sky, like clouds
+	AirLayer
+	// cellLayerCount is the number of layers, it must stay last
+	cellLayerCount
+)
+
+// CellLayers returns every layer from the bottom up.
+func CellLayers() []CellLayer {
+	layers := make([]CellLayer, cellLayerCount)
+	for i := range layers {
+		layers[i] = CellLayer(i)
+	}
+	return layers
+}
+
 // Gameboard tracks the entities in play and the game locations of any solid entities. A single entity may exist at multiple locations. An entity may also not have any game location.
+// A gameboard isn't safe to use from more than one goroutine at a time, whatever runs the simulation owns it.
 type Gameboard interface {
-	// MoveEntity moves the entity at (px,py) to (x,y), (px,py) will be empty after this
-	MoveEntity(px int, py int, x int, y int)
+	// MoveEntity moves the entity in layer at (px,py) to the same layer at (x,y), that layer of (px,py) will be empty after this
+	MoveEntity(layer CellLayer, px int, py int, x int, y int)
 
-	// AddEntity add the entity to the list that Gameboard tracks. It will also call the entity's AddToBoard.
+	// AddEntity add the entity to the list that Gameboard tracks and gives it an ID. It will also call the entity's
+	// AddToBoard. Adding an entity that is already on the board does nothing. Entities added while a tick is running
+	// mark their positions straight away but only join the list, and start being updated, once the tick ends.
 	AddEntity(e Entity)
 
-	// Entities returns a channel of all entities in the board at time of calling. Changes to the entity list after calling will not affect the channel's contents.
-	Entities() <-chan Entity
+	// ID returns the ID the entity was given when it was added, or NoEntity if it isn't on the board. IDs are never
+	// reused and are kept by snapshots.
+	ID(e Entity) EntityID
+
+	// EntityByID returns the entity with the given ID, or nil if there isn't one on the board
+	EntityByID(id EntityID) Entity
+
+	// Every calls fn on every tick where tick-phase is a multiple of period, right before owner's turn to update in
+	// the tick. The timer is dropped when owner is removed. Timers aren't saved in snapshots, entities register them
+	// again from Restore.
+	Every(owner Entity, period int, phase int, fn func())
 
-	// SetEntity puts e at the game location (x,y)
-	SetEntity(e Entity, x int, y int)
+	// Find returns every entity on the board that match returns true for, in the order they were added
+	Find(match func(Entity) bool) []Entity
 
-	// EntityAt returns the entity at the game location (x,y)
+	// AppendEntities appends every entity on the board at the time of calling to dst and returns the result. Changes
+	// to the entity list after calling don't affect it. Passing the last result sliced to [:0] reuses its storage.
+	AppendEntities(dst []Entity) []Entity
+
+	// SetEntity puts e in layer at the game location (x,y), a nil e empties that layer
+	SetEntity(layer CellLayer, e Entity, x int, y int)
+
+	// EntityAt returns the entity in the lowest occupied layer of the game location (x,y), or nil if every layer is empty
 	EntityAt(x int, y int) Entity
 
+	// EntityOn returns the entity in layer at the game location (x,y)
+	EntityOn(layer CellLayer, x int, y int) Entity
+
+	// EntitiesAt returns every entity at the game location (x,y) from the bottom layer up
+	EntitiesAt(x int, y int) []Entity
+
+	// EntityOnChecked is EntityOn for locations that may be off the board, it returns an error wrapping
+	// ErrOutOfBounds instead of panicking
+	EntityOnChecked(layer CellLayer, x int, y int) (Entity, error)
+
+	// SetEntityChecked is SetEntity for locations that may be off the board, it returns an error wrapping
+	// ErrOutOfBounds instead of panicking
+	SetEntityChecked(layer CellLayer, e Entity, x int, y int) error
+
+	// MoveEntityChecked is MoveEntity for locations that may be off the board, it returns an error wrapping
+	// ErrOutOfBounds instead of panicking and nothing is moved
+	MoveEntityChecked(layer CellLayer, px int, py int, x int, y int) error
+
+	// InBounds returns true if (x,y) is a location on the board
+	InBounds(x int, y int) bool
+
+	// Boundary returns what happens to entities that cross edge
+	Boundary(edge Edge) Boundary
+
+	// SetBoundary changes what happens to entities that cross edge
+	SetBoundary(edge Edge, boundary Boundary)
+
+	// Locate applies the boundary policy to a location an entity wants to move to. Locations on the board are returned
+	// unchanged with NoBoundary, as are locations that wrap, after being moved onto the board. Otherwise the location
+	// is returned unchanged with the Wall or Drain of the edge it crossed.
+	Locate(x int, y int) (int, int, Boundary)
+
 	// Size returns the width and height of the game board
 	Size() (width int, height int)
 
-	// RemoveEntity takes the given entity out of the entity list
+	// RemoveEntity takes the given entity out of the entity list. An entity removed while a tick is running is not
+	// updated again, even if its turn in the tick hasn't come yet.
 	RemoveEntity(e Entity)
+
+	// RemoveByID takes the entity with the given ID out of the entity list
+	RemoveByID(id EntityID)
+
+	// Rand returns the random source entities on the board must use, so that runs with the same seed are reproducible
+	Rand() *rand.Rand
+
+	// Triggered returns true if the player triggered action for the current tick. Entities must use this rather than
+	// reading input themselves so that sessions can be recorded and replayed.
+	Triggered(action Action) bool
+
+	// Subscribe registers handler to be called with every event published on the board, including the board's own
+	// EntityAdded, EntityRemoved, EntityMoved and CellChanged events. Handlers are called synchronously by whatever
+	// published the event, usually in the middle of a tick, so they must be quick. The returned function unsubscribes.
+	Subscribe(handler func(Event)) (unsubscribe func())
+
+	// Publish sends e to every subscriber.
+	Publish(e Event)
 }
 
 type gameboard struct {
-	entityLock sync.RWMutex
-	entities   []Entity
-	board      [][]Entity
+	events
+	// entities holds the entities in the order they were added. Removing an entity leaves a nil in its slot so
+	// removal doesn't have to shift the list, the slots are compacted once enough of them are empty.
+	entities []Entity
+	removed  int
+	ids      map[Entity]EntityID
+	slots    map[EntityID]int
+	nextID   EntityID
+	// ticking is set while the simulator updates the entities, adds and removes made meanwhile are queued until the
+	// tick ends so the list being walked doesn't change
+	ticking    bool
+	queued     []queuedChange
+	timers     map[EntityID][]timer
+	board      [][][cellLayerCount]Entity
+	boundaries [edgeCount]Boundary
+	source     *source
+	rand       *rand.Rand
+	actions    map[Action]bool
+}
+
+// NewGameboard gives a simple implementation of Gameboard with the given width and height. The board's
+// random source is seeded with seed.
+func NewGameboard(width int, height int, seed int64) Gameboard {
+	return newGameboard(width, height, seed)
 }
 
-// NewGameboard gives a simple implementation of Gameboard with the given width and height
-func NewGameboard(width int, height int) Gameboard {
+func newGameboard(width int, height int, seed int64) *gameboard {
 	g := &gameboard{
-		board: make([][]Entity, width),
+		ids:        map[Entity]EntityID{},
+		slots:      map[EntityID]int{},
+		timers:     map[EntityID][]timer{},
+		nextID:     1,
+		board:      make([][][cellLayerCount]Entity, width),
+		boundaries: defaultBoundaries,
+		source:     newSource(seed),
 	}
+	g.rand = rand.New(g.source)
 	for i := range g.board {
-		g.board[i] = make([]Entity, height)
+		g.board[i] = make([][cellLayerCount]Entity, height)
 	}
 	return g
 }
 
 // RemoveEntity takes the given entity out of the entity list, the caller is expected to have
-// already removed the entity's game locations using SetEntity(nil,x,y) for all locations it occupied.
+// already removed the entity's game locations using SetEntity(layer,nil,x,y) for all locations it occupied.
 func (g *gameboard) RemoveEntity(e Entity) {
-	g.entityLock.Lock()
-	defer g.entityLock.Unlock()
-	for i, ent := range g.entities {
-		if e == ent {
-			g.entities = append(g.entities[:i], g.entities[i+1:]...)
+	if id, ok := g.ids[e]; ok {
+		g.RemoveByID(id)
+	}
+}
+
+func (g *gameboard) RemoveByID(id EntityID) {
+	e := g.entityByID(id)
+	if e == nil {
+		return
+	}
+	// the entity is off the board as soon as its ID is gone, its slot is only cleared once the tick is over
+	delete(g.ids, e)
+	delete(g.timers, id)
+	if g.ticking {
+		g.queued = append(g.queued, queuedChange{id: id})
+	} else {
+		g.drop(id)
+		g.compact()
+	}
+
+	g.Publish(EntityRemoved{Entity: e, ID: id})
+}
+
+// insert puts e at the end of the entity list
+func (g *gameboard) insert(id EntityID, e Entity) {
+	g.slots[id] = len(g.entities)
+	g.entities = append(g.entities, e)
+}
+
+// drop clears the slot of the entity with the given ID
+func (g *gameboard) drop(id EntityID) {
+	slot, ok := g.slots[id]
+	if !ok {
+		// it was added and removed in the same tick, it never made it into the list
+		return
+	}
+	g.entities[slot] = nil
+	delete(g.slots, id)
+	g.removed++
+}
+
+// compact drops the empty slots left by removed entities once enough of the list is empty. It must only be called
+// when no removals are queued.
+func (g *gameboard) compact() {
+	if g.removed <= len(g.entities)/2 {
+		return
+	}
+	entities := make([]Entity, 0, len(g.entities)-g.removed)
+	for _, e := range g.entities {
+		if e != nil {
+			g.slots[g.ids[e]] = len(entities)
+			entities = append(entities, e)
+		}
+	}
+	g.entities = entities
+	g.removed = 0
+}
+
+// live returns true if slot i holds an entity that is on the board. Entities removed during a tick stay in their
+// slot until it ends, so the slot has to match the one the entity's ID points to.
+func (g *gameboard) live(i int) bool {
+	e := g.entities[i]
+	if e == nil {
+		return false
+	}
+	id, ok := g.ids[e]
+	if !ok {
+		return false
+	}
+	slot, ok := g.slots[id]
+	return ok && slot == i
+}
+
+// entityByID returns the entity with the given ID, including ones added during the current tick that haven't joined
+// the list yet.
+func (g *gameboard) entityByID(id EntityID) Entity {
+	if slot, ok := g.slots[id]; ok && g.live(slot) {
+		return g.entities[slot]
+	}
+	for _, change := range g.queued {
+		if change.id == id && change.entity != nil && g.ids[change.entity] == id {
+			return change.entity
+		}
+	}
+	return nil
+}
+
+// queuedChange is an add or remove made while a tick is running. Removals have no entity.
+type queuedChange struct {
+	id     EntityID
+	entity Entity
+}
+
+// tick calls update with every entity on the board that is due on tick number n, in the order they were added, and runs
+// each entity's due timers just before its turn. Entities added during the tick are queued and join the list once it
+// ends, so they aren't updated until the next tick. Entities removed during the tick are skipped from the moment they
+// are removed.
+func (g *gameboard) tick(n int, update func(e Entity)) {
+	g.ticking = true
+	count := len(g.entities)
+
+	for i := 0; i < count; i++ {
+		if !g.live(i) {
+			continue
+		}
+		e := g.entities[i]
+		for _, t := range g.timers[g.ids[e]] {
+			if due(n, t.period, t.phase) {
+				t.fn()
+			}
+		}
+
+		// a timer may have removed the entity
+		if g.live(i) && updateDue(e, n) {
+			update(e)
+		}
+	}
+
+	g.ticking = false
+	for _, change := range g.queued {
+		if change.entity == nil {
+			g.drop(change.id)
+		} else if g.ids[change.entity] == change.id {
+			g.insert(change.id, change.entity)
 		}
 	}
+	g.queued = g.queued[:0]
+	g.compact()
 }
 
-func (g *gameboard) MoveEntity(px int, py int, x int, y int) {
-	g.board[x][y] = g.board[px][py]
-	g.board[px][py] = nil
+func (g *gameboard) ID(e Entity) EntityID {
+	return g.ids[e]
 }
 
-func (g *gameboard) Entities() <-chan Entity {
-	g.entityLock.Lock()
-	defer g.entityLock.Unlock()
+func (g *gameboard) EntityByID(id EntityID) Entity {
+	return g.entityByID(id)
+}
 
-	c := make(chan Entity, len(g.entities))
-	for _, value := range g.entities {
-		c <- value
+func (g *gameboard) Every(owner Entity, period int, phase int, fn func()) {
+	id, ok := g.ids[owner]
+	if !ok {
+		return
 	}
-	close(c)
+	g.timers[id] = append(g.timers[id], timer{period: period, phase: phase, fn: fn})
+}
 
-	return c
+func (g *gameboard) Find(match func(Entity) bool) []Entity {
+	found := []Entity{}
+	for _, e := range g.AppendEntities(nil) {
+		if match(e) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func (g *gameboard) MoveEntity(layer CellLayer, px int, py int, x int, y int) {
+	e := g.board[px][py][layer]
+	g.board[x][y][layer] = e
+	g.board[px][py][layer] = nil
+	g.Publish(EntityMoved{Entity: e, Layer: layer, FromX: px, FromY: py, X: x, Y: y})
+}
+
+// AppendEntities only includes the entities in the list, ones added during the current tick aren't included until it
+// ends
+func (g *gameboard) AppendEntities(dst []Entity) []Entity {
+	for i, value := range g.entities {
+		if g.live(i) {
+			dst = append(dst, value)
+		}
+	}
+	return dst
 }
 
 func (g *gameboard) AddEntity(e Entity) {
-	g.entityLock.Lock()
-	defer g.entityLock.Unlock()
-	g.entities = append(g.entities, e)
+	if _, ok := g.ids[e]; ok {
+		return
+	}
+	id := g.nextID
+	g.nextID++
+	g.ids[e] = id
+	if g.ticking {
+		g.queued = append(g.queued, queuedChange{id: id, entity: e})
+	} else {
+		g.insert(id, e)
+	}
+
 	e.AddToBoard(g)
+	g.Publish(EntityAdded{Entity: e, ID: id})
 }
 
-func (g *gameboard) SetEntity(e Entity, x int, y int) {
-	g.board[x][y] = e
+func (g *gameboard) SetEntity(layer CellLayer, e Entity, x int, y int) {
+	old := g.board[x][y][layer]
+	if old == e {
+		return
+	}
+	g.board[x][y][layer] = e
+	// cells change far more often than anything else, don't make the event unless someone is listening
+	if len(g.subscriptions) > 0 {
+		g.Publish(CellChanged{X: x, Y: y, Layer: layer, Old: old, New: e})
+	}
 }
 
 func (g *gameboard) EntityAt(x int, y int) Entity {
-	return g.board[x][y]
+	for _, e := range g.board[x][y] {
+		if e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func (g *gameboard) EntityOn(layer CellLayer, x int, y int) Entity {
+	return g.board[x][y][layer]
+}
+
+func (g *gameboard) EntitiesAt(x int, y int) []Entity {
+	entities := []Entity{}
+	for _, e := range g.board[x][y] {
+		if e != nil {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (g *gameboard) EntityOnChecked(layer CellLayer, x int, y int) (Entity, error) {
+	if !g.InBounds(x, y) {
+		return nil, outOfBounds(x, y)
+	}
+	return g.EntityOn(layer, x, y), nil
+}
+
+func (g *gameboard) SetEntityChecked(layer CellLayer, e Entity, x int, y int) error {
+	if !g.InBounds(x, y) {
+		return outOfBounds(x, y)
+	}
+	g.SetEntity(layer, e, x, y)
+	return nil
+}
+
+func (g *gameboard) MoveEntityChecked(layer CellLayer, px int, py int, x int, y int) error {
+	if !g.InBounds(px, py) {
+		return outOfBounds(px, py)
+	}
+	if !g.InBounds(x, y) {
+		return outOfBounds(x, y)
+	}
+	g.MoveEntity(layer, px, py, x, y)
+	return nil
+}
+
+func (g *gameboard) InBounds(x int, y int) bool {
+	return x >= 0 && x < len(g.board) && y >= 0 && y < len(g.board[0])
+}
+
+func (g *gameboard) Boundary(edge Edge) Boundary {
+	return g.boundaries[edge]
+}
+
+func (g *gameboard) SetBoundary(edge Edge, boundary Boundary) {
+	g.boundaries[edge] = boundary
+}
+
+func (g *gameboard) Locate(x int, y int) (int, int, Boundary) {
+	width, height := g.Size()
+	edge := edgeCount
+	switch {
+	case x < 0:
+		edge = EdgeLeft
+	case x >= width:
+		edge = EdgeRight
+	case y < 0:
+		edge = EdgeTop
+	case y >= height:
+		edge = EdgeBottom
+	}
+	if edge == edgeCount {
+		return x, y, NoBoundary
+	}
+
+	boundary := g.boundaries[edge]
+	if boundary == Wrap {
+		if edge == EdgeLeft || edge == EdgeRight {
+			x = wrap(x, width)
+		} else {
+			y = wrap(y, height)
+		}
+		// a location past a corner crosses two edges, the other one may not wrap
+		return g.Locate(x, y)
+	}
+	return x, y, boundary
 }
 
 func (g *gameboard) Size() (int, int) {
 	return len(g.board), len(g.board[0])
 }
+
+func (g *gameboard) Rand() *rand.Rand {
+	return g.rand
+}
+
+func (g *gameboard) Triggered(action Action) bool {
+	return g.actions[action]
+}
@@ -0,0 +1,224 @@
+package game
+
+import (
+	"testing"
+)
+
+// testEntity counts its updates and calls onUpdate from each one
+type testEntity struct {
+	updates  int
+	onUpdate func()
+}
+
+func (e *testEntity) Draw(r Renderer) {}
+
+func (e *testEntity) Update() {
+	e.updates++
+	if e.onUpdate != nil {
+		e.onUpdate()
+	}
+}
+
+func (e *testEntity) AddToBoard(gameboard Gameboard) {}
+
+func (e *testEntity) Layer() int {
+	return 0
+}
+
+// newTestBoard returns a board holding n test entities
+func newTestBoard(n int) (*gameboard, []*testEntity) {
+	g := newGameboard(10, 10, 1)
+	entities := make([]*testEntity, n)
+	for i := range entities {
+		entities[i] = &testEntity{}
+		g.AddEntity(entities[i])
+	}
+	return g, entities
+}
+
+// step runs tick n of g, updating every due entity
+func step(g *gameboard, n int) {
+	g.tick(n, func(e Entity) {
+		e.Update()
+	})
+}
+
+// onBoard returns true if e is in g's entity list
+func onBoard(g *gameboard, e Entity) bool {
+	for _, other := range g.AppendEntities(nil) {
+		if other == e {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRemovedMidTickIsNotUpdated(t *testing.T) {
+	g, entities := newTestBoard(3)
+	first, last := entities[0], entities[2]
+	first.onUpdate = func() {
+		g.RemoveEntity(last)
+		if onBoard(g, last) {
+			t.Error("the removed entity is still listed during the tick")
+		}
+	}
+
+	step(g, 1)
+	if last.updates != 0 {
+		t.Errorf("the entity removed earlier in the tick was updated %d times", last.updates)
+	}
+	first.onUpdate = nil
+	step(g, 2)
+	if last.updates != 0 || onBoard(g, last) || g.ID(last) != NoEntity {
+		t.Errorf("the removed entity is still on the board: %d updates, ID %d", last.updates, g.ID(last))
+	}
+	if entities[1].updates != 2 {
+		t.Errorf("the entity after it was updated %d times, want 2", entities[1].updates)
+	}
+}
+
+func TestAddedMidTickWaitsForNextTick(t *testing.T) {
+	g, entities := newTestBoard(2)
+	added := &testEntity{}
+	entities[0].onUpdate = func() {
+		g.AddEntity(added)
+		if onBoard(g, added) {
+			t.Error("the added entity is listed before the tick ends")
+		}
+		if g.EntityByID(g.ID(added)) != added {
+			t.Error("the added entity can't be found by its ID during the tick")
+		}
+		entities[0].onUpdate = nil
+	}
+
+	step(g, 1)
+	if added.updates != 0 {
+		t.Errorf("the entity added during the tick was updated %d times in it", added.updates)
+	}
+	if !onBoard(g, added) {
+		t.Fatal("the added entity isn't listed once the tick ends")
+	}
+	step(g, 2)
+	if added.updates != 1 {
+		t.Errorf("the added entity was updated %d times in the next tick, want 1", added.updates)
+	}
+}
+
+func TestAddThenRemoveInOneTick(t *testing.T) {
+	g, entities := newTestBoard(2)
+	added := &testEntity{}
+	var id EntityID
+	entities[0].onUpdate = func() {
+		g.AddEntity(added)
+		id = g.ID(added)
+		g.RemoveEntity(added)
+		entities[0].onUpdate = nil
+	}
+
+	step(g, 1)
+	step(g, 2)
+	if added.updates != 0 {
+		t.Errorf("the entity was updated %d times", added.updates)
+	}
+	if onBoard(g, added) || g.ID(added) != NoEntity || g.EntityByID(id) != nil {
+		t.Error("the entity is still on the board")
+	}
+	if len(g.entities) != 2 || len(g.slots) != 2 || len(g.queued) != 0 || g.removed != 0 {
+		t.Errorf("the board kept a trace of the entity: %d slots, %d IDs, %d queued, %d removed", len(g.entities),
+			len(g.slots), len(g.queued), g.removed)
+	}
+	next := &testEntity{}
+	g.AddEntity(next)
+	if g.ID(next) != id+1 {
+		t.Errorf("the next entity got ID %d, want %d, IDs are never reused", g.ID(next), id+1)
+	}
+}
+
+// otherEntity is a second entity type for type queries
+type otherEntity struct {
+	testEntity
+}
+
+func TestIDsSurviveCompaction(t *testing.T) {
+	g, entities := newTestBoard(10)
+	ids := make([]EntityID, len(entities))
+	for i, e := range entities {
+		ids[i] = g.ID(e)
+	}
+
+	// removing more than half the entities, some during a tick, compacts the list
+	for _, i := range []int{0, 2, 3} {
+		g.RemoveEntity(entities[i])
+	}
+	entities[1].onUpdate = func() {
+		for _, i := range []int{5, 6, 8} {
+			g.RemoveEntity(entities[i])
+		}
+		entities[1].onUpdate = nil
+	}
+	step(g, 1)
+	if len(g.entities) != 4 || g.removed != 0 {
+		t.Fatalf("the list has %d slots with %d removed, want it compacted to 4", len(g.entities), g.removed)
+	}
+
+	for i, e := range entities {
+		removed := i == 0 || i == 2 || i == 3 || i == 5 || i == 6 || i == 8
+		if removed {
+			if g.ID(e) != NoEntity || g.EntityByID(ids[i]) != nil {
+				t.Errorf("entity %d is still on the board", i)
+			}
+			continue
+		}
+		if g.ID(e) != ids[i] || g.EntityByID(ids[i]) != e {
+			t.Errorf("entity %d has ID %d after compacting, want %d", i, g.ID(e), ids[i])
+		}
+	}
+	if got, want := g.AppendEntities(nil), []Entity{entities[1], entities[4], entities[7], entities[9]}; !sameEntities(got, want) {
+		t.Errorf("the list is %v after compacting, want %v", got, want)
+	}
+}
+
+func TestOfTypeKeepsOrder(t *testing.T) {
+	g := newGameboard(10, 10, 1)
+	var tests, others []Entity
+	for i := 0; i < 12; i++ {
+		if i%3 == 0 {
+			e := &otherEntity{}
+			others = append(others, e)
+			g.AddEntity(e)
+		} else {
+			e := &testEntity{}
+			tests = append(tests, e)
+			g.AddEntity(e)
+		}
+	}
+	g.RemoveEntity(tests[0])
+	g.RemoveEntity(tests[3])
+	g.RemoveEntity(others[1])
+	tests = append(tests[1:3], tests[4:]...)
+	others = append(others[:1], others[2:]...)
+	// entities added after the removals go on the end
+	late := &testEntity{}
+	g.AddEntity(late)
+	tests = append(tests, late)
+
+	if got := OfType(g, (*testEntity)(nil)); !sameEntities(got, tests) {
+		t.Errorf("OfType returned %v, want %v", got, tests)
+	}
+	if got := OfType(g, (*otherEntity)(nil)); !sameEntities(got, others) {
+		t.Errorf("OfType returned %v, want %v", got, others)
+	}
+}
+
+// sameEntities returns true if a and b hold the same entities in the same order
+func sameEntities(a []Entity, b []Entity) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+package game_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"github.com/tannerhat/Cactus-Simulator/nature"
+)
+
+// stormyConfig rains often enough that a short run has clouds, rain and wet soil
+func stormyConfig() nature.Config {
+	config := nature.DefaultConfig()
+	config.Weather.CloudSpawn = 100
+	config.Weather.RainStart = 10
+	return config
+}
+
+// newWorld returns the default world, built with config from seed
+func newWorld(t testing.TB, config nature.Config, seed int64) *game.Simulator {
+	t.Helper()
+	s := nature.DefaultScenario()
+	entities, err := s.Build(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := game.NewSimulator(s.Width, s.Height, seed)
+	for _, e := range entities {
+		sim.AddEntity(e)
+	}
+	return sim
+}
+
+// save returns the snapshot of sim
+func save(t testing.TB, sim *game.Simulator) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := sim.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// runInStep runs a and b side by side for rounds of ticks each and fails as soon as their snapshots differ
+func runInStep(t *testing.T, a *game.Simulator, b *game.Simulator, rounds int, ticks int) {
+	t.Helper()
+	for i := 0; i < rounds; i++ {
+		a.Run(ticks, false)
+		b.Run(ticks, false)
+		if !bytes.Equal(save(t, a), save(t, b)) {
+			t.Fatalf("the worlds differ after %d ticks", a.Ticks())
+		}
+	}
+}
+
+// draw returns a picture of sim's board drawn through r at one pixel per cell
+func draw(sim *game.Simulator, r *game.ImageRenderer) []byte {
+	r.Clear()
+	game.DrawBoard(r, sim.Gameboard())
+	return append([]byte(nil), r.Image().Pix...)
+}
+
+// groundAt returns the entity in the ground layer at (x,y)
+func groundAt(sim *game.Simulator, x int, y int) game.Entity {
+	return sim.Gameboard().EntityOn(game.GroundLayer, x, y)
+}
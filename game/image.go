@@ -0,0 +1,110 @@
+package game
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ImageRenderer is a Renderer that draws into an image.RGBA, for showing the board without a window.
+type ImageRenderer struct {
+	image *image.RGBA
+	scale int
+	face  font.Face
+}
+
+// NewImageRenderer returns a renderer for a board of width x height cells, drawn scale pixels per cell. Text is drawn
+// with a small fixed size font until SetFace is called.
+func NewImageRenderer(width int, height int, scale int) *ImageRenderer {
+	return &ImageRenderer{
+		image: image.NewRGBA(image.Rect(0, 0, width*scale, height*scale)),
+		scale: scale,
+		face:  basicfont.Face7x13,
+	}
+}
+
+// Image returns the image being drawn into.
+func (r *ImageRenderer) Image() *image.RGBA {
+	return r.image
+}
+
+// SetFace changes the font DrawText uses.
+func (r *ImageRenderer) SetFace(face font.Face) {
+	r.face = face
+}
+
+// Clear makes the whole image transparent so a new frame can be drawn.
+func (r *ImageRenderer) Clear() {
+	draw.Draw(r.image, r.image.Bounds(), image.Transparent, image.Point{}, draw.Src)
+}
+
+func (r *ImageRenderer) FillCell(x int, y int, c color.Color) {
+	r.FillRect(x, y, 1, 1, c)
+}
+
+func (r *ImageRenderer) FillRect(x int, y int, width int, height int, c color.Color) {
+	rect := image.Rect(x*r.scale, y*r.scale, (x+width)*r.scale, (y+height)*r.scale)
+	// cells are blended over what is already drawn the same way the window draws them
+	draw.Draw(r.image, rect, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+func (r *ImageRenderer) DrawBuffer(b *CellBuffer) {
+	src := b.Image()
+	if r.scale == 1 {
+		draw.Draw(r.image, src.Bounds().Add(image.Pt(b.X, b.Y)), src, image.Point{}, draw.Over)
+		return
+	}
+
+	bounds := r.image.Bounds()
+	width, height := b.Size()
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := src.RGBAAt(x, y)
+			if c.A == 0 {
+				continue
+			}
+			if c.A < 0xff {
+				r.FillCell(b.X+x, b.Y+y, c)
+				continue
+			}
+			// opaque cells replace what is under them, so their pixels can be written directly
+			cell := image.Rect((b.X+x)*r.scale, (b.Y+y)*r.scale, (b.X+x+1)*r.scale, (b.Y+y+1)*r.scale).Intersect(bounds)
+			if cell.Empty() {
+				continue
+			}
+			first := r.image.Pix[r.image.PixOffset(cell.Min.X, cell.Min.Y):r.image.PixOffset(cell.Max.X, cell.Min.Y)]
+			for i := 0; i < len(first); i += 4 {
+				first[i], first[i+1], first[i+2], first[i+3] = c.R, c.G, c.B, c.A
+			}
+			for py := cell.Min.Y + 1; py < cell.Max.Y; py++ {
+				copy(r.image.Pix[r.image.PixOffset(cell.Min.X, py):], first)
+			}
+		}
+	}
+}
+
+func (r *ImageRenderer) DrawText(s string, x int, y int, c color.Color) {
+	d := font.Drawer{
+		Dst:  r.image,
+		Src:  image.NewUniform(c),
+		Face: r.face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(s)
+}
+
+func (r *ImageRenderer) Scale() int {
+	return r.scale
+}
+
+// BoardImage draws g with one pixel per cell, independent of the scale it is shown at.
+func BoardImage(g Gameboard) *image.RGBA {
+	width, height := g.Size()
+	r := NewImageRenderer(width, height, 1)
+	DrawBoard(r, g)
+	return r.Image()
+}
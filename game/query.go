@@ -0,0 +1,27 @@
+package game
+
+import (
+	"reflect"
+)
+
+// OfType returns every entity on the board with the same concrete type as example, a nil pointer works as the example,
+// e.g. OfType(g, (*nature.Cloud)(nil)).
+func OfType(g Gameboard, example Entity) []Entity {
+	t := reflect.TypeOf(example)
+	return g.Find(func(e Entity) bool {
+		return reflect.TypeOf(e) == t
+	})
+}
+
+// Implementing returns every entity on the board that implements the interface iface points to, e.g.
+// Implementing(g, (*Winnable)(nil)). It panics if iface isn't a pointer to an interface type.
+func Implementing(g Gameboard, iface interface{}) []Entity {
+	t := reflect.TypeOf(iface)
+	if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Interface {
+		panic("Implementing needs a pointer to an interface type")
+	}
+	t = t.Elem()
+	return g.Find(func(e Entity) bool {
+		return reflect.TypeOf(e).Implements(t)
+	})
+}
@@ -0,0 +1,40 @@
+package game
+
+import (
+	"math/rand"
+)
+
+// source is a splitmix64 rand.Source64. Unlike the sources in math/rand, its whole state is a single
+// number so it can be written to a snapshot and restored exactly.
+type source struct {
+	state uint64
+}
+
+func newSource(seed int64) *source {
+	s := &source{}
+	s.Seed(seed)
+	return s
+}
+
+func (s *source) Seed(seed int64) {
+	s.state = uint64(seed)
+}
+
+func (s *source) Uint64() uint64 {
+	s.state += 0x9e3779b97f4a7c15
+	z := s.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *source) Int63() int64 {
+	return int64(s.Uint64() >> 1)
+}
+
+// NewRand returns a random number generator backed by the same kind of source as the gameboard's. Seeding it is cheap,
+// so entities that split their work across goroutines can give each worker its own generator and reseed it every tick
+// from the gameboard's Rand.
+func NewRand(seed int64) *rand.Rand {
+	return rand.New(newSource(seed))
+}
@@ -0,0 +1,59 @@
+package game
+
+import (
+	"image/color"
+	"sync"
+)
+
+// Renderer is what entities draw themselves through, so they don't depend on how or where the board is shown.
+// Positions given to FillCell and FillRect are gameboard coordinates, the renderer turns them into pixels.
+type Renderer interface {
+	// FillCell fills the gameboard cell (x,y) with c
+	FillCell(x int, y int, c color.Color)
+
+	// FillRect fills the width x height block of gameboard cells with its top left corner at (x,y) with c
+	FillRect(x int, y int, width int, height int, c color.Color)
+
+	// DrawBuffer draws every cell of b at its place on the board
+	DrawBuffer(b *CellBuffer)
+
+	// DrawText draws s in c with the left end of its baseline at the pixel (x,y)
+	DrawText(s string, x int, y int, c color.Color)
+
+	// Scale returns the number of pixels on each side of a gameboard cell
+	Scale() int
+}
+
+// drawLists holds entity lists for DrawBoard to reuse so drawing a frame doesn't allocate one
+var drawLists = sync.Pool{
+	New: func() interface{} { return new([]Entity) },
+}
+
+// DrawBoard draws every entity on the board through r. Entities are drawn in order of their Layer, lowest first, and
+// in the order they were added within a layer.
+func DrawBoard(r Renderer, g Gameboard) {
+	list := drawLists.Get().(*[]Entity)
+	entityList := g.AppendEntities((*list)[:0])
+	maxLayer := 0
+	for _, e := range entityList {
+		if e.Layer() > maxLayer {
+			maxLayer = e.Layer()
+		}
+	}
+
+	// the list is taken once up front because drawing could change the entities on the board
+	for layer := 0; layer <= maxLayer; layer++ {
+		for _, e := range entityList {
+			if e.Layer() == layer {
+				e.Draw(r)
+			}
+		}
+	}
+
+	// clear the list so the pool doesn't keep removed entities alive
+	for i := range entityList {
+		entityList[i] = nil
+	}
+	*list = entityList[:0]
+	drawLists.Put(list)
+}
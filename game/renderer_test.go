@@ -0,0 +1,34 @@
+package game_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+func TestCachedDrawMatchesRedraw(t *testing.T) {
+	sim := newWorld(t, stormyConfig(), 3)
+	width, height := sim.Gameboard().Size()
+	cached := game.NewImageRenderer(width, height, 1)
+	// the entities make their pictures on the first draw and only change the cells that change after that
+	draw(sim, cached)
+	for i := 0; i < 30; i++ {
+		sim.Run(100, false)
+		if i == 10 {
+			if ok, err := sim.UseTool(game.ToolUse{Tool: "dig", X: 5, Y: 40}); !ok || err != nil {
+				t.Fatalf("dig returned %t, %v", ok, err)
+			}
+		}
+		got := draw(sim, cached)
+
+		// a copy of the world draws every picture from scratch
+		fresh, err := game.LoadSimulator(bytes.NewReader(save(t, sim)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := draw(fresh, game.NewImageRenderer(width, height, 1)); !bytes.Equal(got, want) {
+			t.Fatalf("after %d ticks the cached pictures differ from a full redraw", sim.Ticks())
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package game
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ReplayVersion is the version of the replay format written by Replay.Save. Version 2 added tool uses. Replays with a
+// different version, or that start from a snapshot LoadSimulator can't load, are rejected when loading.
+const ReplayVersion = 2
+
+// Replay is a recording of a session. It holds a snapshot of the world when recording started and every action that
+// was taken after, so the session can be watched again or re-simulated.
+type Replay struct {
+	Version int
+	Start   json.RawMessage
+	Events  []ReplayEvent
+}
+
+// ReplayEvent is an action stamped with the tick it took effect on. Uses of tools are ActionUseTool events that say
+// which tool was used where.
+type ReplayEvent struct {
+	Tick   int
+	Action Action
+	Tool   *ToolUse `json:",omitempty"`
+}
+
+// NewReplay starts a recording of sim from its current state.
+func NewReplay(sim *Simulator) (*Replay, error) {
+	var start bytes.Buffer
+	if err := sim.Save(&start); err != nil {
+		return nil, err
+	}
+	r := &Replay{
+		Version: ReplayVersion,
+		Start:   start.Bytes(),
+		Events:  []ReplayEvent{},
+	}
+	return r, nil
+}
+
+// Record adds an action that takes effect on the given tick.
+func (r *Replay) Record(tick int, action Action) {
+	r.Events = append(r.Events, ReplayEvent{Tick: tick, Action: action})
+}
+
+// RecordTool adds a use of a tool that takes effect on the given tick.
+func (r *Replay) RecordTool(tick int, use ToolUse) {
+	r.Events = append(r.Events, ReplayEvent{Tick: tick, Action: ActionUseTool, Tool: &use})
+}
+
+// Save writes the replay to w.
+func (r *Replay) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// LoadReplay reads a replay written by Save.
+func LoadReplay(r io.Reader) (*Replay, error) {
+	var replay Replay
+	if err := json.NewDecoder(r).Decode(&replay); err != nil {
+		return nil, fmt.Errorf("reading replay: %v", err)
+	}
+	if replay.Version != ReplayVersion {
+		return nil, fmt.Errorf("replay is version %d, only version %d is supported", replay.Version, ReplayVersion)
+	}
+	// the start is only restored when the replay is played, check it can be before anything relies on the replay
+	var start struct{ Version int }
+	if err := json.Unmarshal(replay.Start, &start); err != nil {
+		return nil, fmt.Errorf("reading replay start: %v", err)
+	}
+	if start.Version != SnapshotVersion {
+		return nil, fmt.Errorf("replay starts from a version %d snapshot, only version %d can be played", start.Version,
+			SnapshotVersion)
+	}
+	for i, e := range replay.Events {
+		if i > 0 && e.Tick < replay.Events[i-1].Tick {
+			return nil, fmt.Errorf("replay events are out of order at event %d", i)
+		}
+		if (e.Action == ActionUseTool) != (e.Tool != nil) {
+			return nil, fmt.Errorf("replay event %d: only %s events say which tool was used", i, ActionUseTool)
+		}
+		if e.Tool != nil {
+			if _, ok := tools[e.Tool.Tool]; !ok {
+				return nil, fmt.Errorf("replay event %d: unknown tool %q", i, e.Tool.Tool)
+			}
+		}
+	}
+	return &replay, nil
+}
+
+// Player feeds the actions of a replay back in at the ticks they were recorded on.
+type Player struct {
+	replay *Replay
+	sim    *Simulator
+	next   int
+}
+
+// Play restores the replay's starting snapshot and returns a player for it.
+func (r *Replay) Play() (*Player, error) {
+	sim, err := LoadSimulator(bytes.NewReader(r.Start))
+	if err != nil {
+		return nil, err
+	}
+	p := &Player{
+		replay: r,
+		sim:    sim,
+		next:   0,
+	}
+	return p, nil
+}
+
+// Simulator returns the simulator the replay is being played on.
+func (p *Player) Simulator() *Simulator {
+	return p.sim
+}
+
+// Next returns the events that take effect on the simulator's next tick. Each event is only returned once.
+func (p *Player) Next() []ReplayEvent {
+	tick := p.sim.Ticks() + 1
+	start := p.next
+	for p.next < len(p.replay.Events) && p.replay.Events[p.next].Tick <= tick {
+		// events from before the next tick can only be left over if the replay was edited, apply them late
+		// rather than dropping them
+		p.next++
+	}
+	return p.replay.Events[start:p.next]
+}
+
+// Done returns true once every action in the replay has been returned by Next.
+func (p *Player) Done() bool {
+	return p.next >= len(p.replay.Events)
+}
+
+// UseTool uses a recorded tool on the simulator. Only tools that worked are recorded, so it returns an error if the
+// tool can't be used, the world has gone out of step with the recording.
+func (p *Player) UseTool(use ToolUse) error {
+	used, err := p.sim.UseTool(use)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return fmt.Errorf("replay: %s at (%d,%d) on tick %d did nothing, the world is out of step with the recording",
+			use.Tool, use.X, use.Y, p.sim.Ticks()+1)
+	}
+	return nil
+}
+
+// Step triggers the next tick's simulated actions and steps the simulator, for replaying without a window. It returns
+// true if a Winnable entity won during the tick. If a recorded tool can't be used the tick isn't stepped and the
+// error is returned.
+func (p *Player) Step() (bool, error) {
+	for _, e := range p.Next() {
+		if e.Tool != nil {
+			if err := p.UseTool(*e.Tool); err != nil {
+				return false, err
+			}
+		} else if e.Action.Simulated() {
+			p.sim.Trigger(e.Action)
+		}
+	}
+	return p.sim.Step(), nil
+}
@@ -0,0 +1,139 @@
+package game_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"github.com/tannerhat/Cactus-Simulator/nature"
+)
+
+func TestReplayMatchesSession(t *testing.T) {
+	sim := newWorld(t, stormyConfig(), 9)
+	sim.Run(1000, false)
+	replay, err := game.NewReplay(sim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// play a session the way the window does, every change is recorded for the tick it takes effect on
+	uses := map[int][]game.ToolUse{
+		200: {{Tool: "place rock", X: 30, Y: 22}, {Tool: "place rock", X: 31, Y: 22}},
+		450: {{Tool: "pour water", X: 80, Y: 10}, {Tool: "dig", X: 5, Y: 40}},
+		900: {{Tool: "seed cloud", X: 60, Y: 3}, {Tool: "dry out", X: 62, Y: 36}},
+	}
+	for i := 0; i < 3000; i++ {
+		if i%300 == 0 {
+			sim.Trigger(game.ActionAbsorb)
+			replay.Record(sim.Ticks()+1, game.ActionAbsorb)
+		}
+		if i%700 == 0 {
+			// recorded but not simulated, playing it back mustn't change the world
+			replay.Record(sim.Ticks()+1, game.ActionDebug)
+		}
+		for _, use := range uses[i] {
+			if used, err := sim.UseTool(use); !used || err != nil {
+				t.Fatalf("%s at (%d,%d) returned %t, %v", use.Tool, use.X, use.Y, used, err)
+			}
+			replay.RecordTool(sim.Ticks()+1, use)
+		}
+		sim.Step()
+	}
+
+	var buf bytes.Buffer
+	if err := replay.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := game.LoadReplay(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Events) != len(replay.Events) {
+		t.Fatalf("the loaded replay has %d events, want %d", len(loaded.Events), len(replay.Events))
+	}
+	player, err := loaded.Play()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for player.Simulator().Ticks() < sim.Ticks() {
+		if _, err := player.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !player.Done() {
+		t.Error("the replay has events left after the session's last tick")
+	}
+	// the recorded tools changed the replayed world the way they changed the session's
+	played := player.Simulator()
+	if rock, ok := groundAt(played, 30, 22).(*nature.Rock); !ok || groundAt(played, 31, 22) != rock {
+		t.Errorf("the replayed world has %T at (30,22) and %T at (31,22), want one rock", groundAt(played, 30, 22), groundAt(played, 31, 22))
+	}
+	if e := groundAt(played, 5, 40); e != nil {
+		t.Errorf("the dug cell (5,40) holds %T in the replayed world", e)
+	}
+	if !bytes.Equal(save(t, played), save(t, sim)) {
+		t.Error("playing the replay back ends in a different world than the session")
+	}
+}
+
+func TestLoadReplayRejectsOldVersions(t *testing.T) {
+	replay, err := game.NewReplay(newWorld(t, nature.DefaultConfig(), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start map[string]json.RawMessage
+	if err := json.Unmarshal(replay.Start, &start); err != nil {
+		t.Fatal(err)
+	}
+	start["Version"] = json.RawMessage(fmt.Sprint(game.SnapshotVersion - 1))
+	oldStart, err := json.Marshal(start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		name    string
+		version int
+		start   json.RawMessage
+		want    string
+	}{
+		{"version 1", 1, replay.Start, "replay is version 1"},
+		{"a newer version", game.ReplayVersion + 1, replay.Start, fmt.Sprintf("replay is version %d", game.ReplayVersion+1)},
+		{"an old snapshot", game.ReplayVersion, oldStart, fmt.Sprintf("version %d snapshot", game.SnapshotVersion-1)},
+	} {
+		var buf bytes.Buffer
+		if err := (&game.Replay{Version: c.version, Start: c.start}).Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := game.LoadReplay(&buf); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("loading a replay of %s: got error %v, want one saying %q", c.name, err, c.want)
+		}
+	}
+}
+
+func TestReplayStopsWhenAToolDoesNothing(t *testing.T) {
+	replay, err := game.NewReplay(newWorld(t, nature.DefaultConfig(), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sky can't be dug, the session that recorded this must have been different
+	replay.RecordTool(3, game.ToolUse{Tool: "dig", X: 5, Y: 2})
+	player, err := replay.Play()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < 3; i++ {
+		if _, err := player.Step(); err != nil {
+			t.Fatalf("tick %d: %v", i, err)
+		}
+	}
+	if _, err := player.Step(); err == nil || !strings.Contains(err.Error(), "out of step") {
+		t.Errorf("got error %v, want one saying the world is out of step", err)
+	}
+	if ticks := player.Simulator().Ticks(); ticks != 2 {
+		t.Errorf("the simulator stepped to tick %d, want it left at 2", ticks)
+	}
+}
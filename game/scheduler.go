@@ -0,0 +1,36 @@
+package game
+
+// Periodic is implemented by entities that don't need updating every tick. The simulator only calls Update on ticks
+// where tick-Phase() is a multiple of Period(). Because that only depends on the tick number, nothing about the
+// schedule has to be saved in snapshots.
+type Periodic interface {
+	// Period returns the number of ticks between updates, a period of 1 or less updates every tick
+	Period() int
+
+	// Phase returns the offset of the updates within the period, so entities sharing a period can be spread out
+	Phase() int
+}
+
+// timer is a callback registered with Gameboard.Every
+type timer struct {
+	period int
+	phase  int
+	fn     func()
+}
+
+// due returns true if something with the given period and phase runs on tick
+func due(tick int, period int, phase int) bool {
+	if period <= 1 {
+		return true
+	}
+	return wrap(tick-phase, period) == 0
+}
+
+// updateDue returns true if e should be updated on tick
+func updateDue(e Entity, tick int) bool {
+	p, ok := e.(Periodic)
+	if !ok {
+		return true
+	}
+	return due(tick, p.Period(), p.Phase())
+}
@@ -2,8 +2,6 @@ package game
 
 import (
 	"image/color"
-
-	"github.com/hajimehoshi/ebiten"
 )
 
 // Shape is a simple non physical entity. Non physical means that it does not take up any space on the gameboard but will still be drawn.
@@ -13,10 +11,14 @@ type Shape struct {
 	// The gameboard Y coordinate of Shape
 	Y int
 	// Cells tracks which of the cells in the box bounded by (X,Y) and (X+width,Y+height) are actually part of the Shape.
+	// Use SetCell to change a single cell so the shape's picture is kept up to date, after changing Cells directly
+	// call Redraw.
 	Cells     [][]bool
 	Gameboard Gameboard
 	color     color.Color
 	layer     int
+	// buffer is the picture of the shape that Draw draws, it is made on the first Draw
+	buffer *CellBuffer
 }
 
 // New shape returns a Shape that is located at gameboard coordinates (x,y) with an empty Cells matrix of size width x height.
@@ -36,18 +38,73 @@ func NewShape(x int, y int, width int, height int, layer int, color color.Color)
 	return s
 }
 
-// Draw the shape to screen. It will be drawn starting at (X*scale,Y*scale). only x,y coordinates where Cells[x][y] is true are drawn.
-func (s *Shape) Draw(screen *ebiten.Image, scale int) {
-	cellImage, _ := ebiten.NewImage(scale, scale, ebiten.FilterDefault)
-	defer cellImage.Dispose()
-	cellImage.Fill(s.color)
+// Draw the shape through r. It will be drawn starting at (X,Y). only x,y coordinates where Cells[x][y] is true are drawn.
+func (s *Shape) Draw(r Renderer) {
+	if s.buffer == nil {
+		s.buffer = NewCellBuffer(s.X, s.Y, s.Width(), s.Height())
+		s.Redraw()
+	} else if width, height := s.buffer.Size(); width != s.Width() || height != s.Height() {
+		// the shape grew or shrank since it was last drawn
+		s.Redraw()
+	}
+	s.buffer.X = s.X
+	s.buffer.Y = s.Y
+	r.DrawBuffer(s.buffer)
+}
 
+// SetCell changes whether the cell (x,y) of the Cells matrix is part of the shape.
+func (s *Shape) SetCell(x int, y int, set bool) {
+	s.Cells[x][y] = set
+	if s.buffer == nil {
+		return
+	}
+	if set {
+		s.buffer.Set(x, y, s.color)
+	} else {
+		s.buffer.Set(x, y, color.Transparent)
+	}
+}
+
+// Grow makes the Cells matrix big enough to cover the gameboard location (x,y), moving X and Y if it grows left or up.
+// The cells it adds aren't part of the shape until they are set.
+func (s *Shape) Grow(x int, y int) {
+	minX, minY, maxX, maxY := s.X, s.Y, s.X+s.Width(), s.Y+s.Height()
+	if x < minX {
+		minX = x
+	} else if x >= maxX {
+		maxX = x + 1
+	}
+	if y < minY {
+		minY = y
+	} else if y >= maxY {
+		maxY = y + 1
+	}
+	if minX == s.X && minY == s.Y && maxX == s.X+s.Width() && maxY == s.Y+s.Height() {
+		return
+	}
+
+	cells := make([][]bool, maxX-minX)
+	for i := range cells {
+		cells[i] = make([]bool, maxY-minY)
+	}
+	for i := range s.Cells {
+		copy(cells[s.X-minX+i][s.Y-minY:], s.Cells[i])
+	}
+	s.X, s.Y, s.Cells = minX, minY, cells
+	s.Redraw()
+}
+
+// Redraw updates the shape's picture from the whole Cells matrix.
+func (s *Shape) Redraw() {
+	if s.buffer == nil {
+		// the picture is drawn from scratch on the first Draw anyway
+		return
+	}
+	s.buffer.Resize(s.Width(), s.Height())
 	for x := range s.Cells {
 		for y := range s.Cells[x] {
 			if s.Cells[x][y] {
-				op := &ebiten.DrawImageOptions{}
-				op.GeoM.Translate(float64((s.X+x)*scale), float64((s.Y+y)*scale))
-				screen.DrawImage(cellImage, op)
+				s.buffer.Set(x, y, s.color)
 			}
 		}
 	}
@@ -63,6 +120,39 @@ func (s *Shape) AddToBoard(gameboard Gameboard) {
 	return
 }
 
+// Occupy puts e in layer at every gameboard location covered by the Cells matrix. Locations off the board or already
+// holding another entity in that layer are left alone.
+func (s *Shape) Occupy(layer CellLayer, e Entity) {
+	width, height := s.Gameboard.Size()
+	for x := range s.Cells {
+		for y := range s.Cells[x] {
+			boardX, boardY := s.X+x, s.Y+y
+			if !s.Cells[x][y] || boardX < 0 || boardX >= width || boardY < 0 || boardY >= height {
+				continue
+			}
+			if s.Gameboard.EntityOn(layer, boardX, boardY) == nil {
+				s.Gameboard.SetEntity(layer, e, boardX, boardY)
+			}
+		}
+	}
+}
+
+// Vacate empties layer at every gameboard location covered by the Cells matrix that holds e.
+func (s *Shape) Vacate(layer CellLayer, e Entity) {
+	width, height := s.Gameboard.Size()
+	for x := range s.Cells {
+		for y := range s.Cells[x] {
+			boardX, boardY := s.X+x, s.Y+y
+			if !s.Cells[x][y] || boardX < 0 || boardX >= width || boardY < 0 || boardY >= height {
+				continue
+			}
+			if s.Gameboard.EntityOn(layer, boardX, boardY) == e {
+				s.Gameboard.SetEntity(layer, nil, boardX, boardY)
+			}
+		}
+	}
+}
+
 func (s *Shape) Width() int {
 	return len(s.Cells)
 }
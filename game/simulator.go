@@ -0,0 +1,109 @@
+package game
+
+// Simulator owns a Gameboard and advances the entities on it one tick at a time. It does not depend on
+// ebiten so it can be used to run the simulation headless, window.Game drives one for the windowed version.
+type Simulator struct {
+	gameboard *gameboard
+	seed      int64
+	ticks     int
+	won       bool
+	// pending holds the actions that will be triggered on the next tick
+	pending map[Action]bool
+}
+
+// NewSimulator creates a simulator with an empty gameboard of the given width and height. Two simulators
+// created with the same seed and given the same entities will produce identical boards.
+func NewSimulator(width int, height int, seed int64) *Simulator {
+	s := &Simulator{
+		gameboard: newGameboard(width, height, seed),
+		seed:      seed,
+		ticks:     0,
+		won:       false,
+		pending:   map[Action]bool{},
+	}
+	return s
+}
+
+// AddEntity adds the given entity to the simulator's board.
+func (s *Simulator) AddEntity(entity Entity) {
+	s.gameboard.AddEntity(entity)
+}
+
+// Gameboard returns the board being simulated.
+func (s *Simulator) Gameboard() Gameboard {
+	return s.gameboard
+}
+
+// HandOver moves everything subscribed to the simulator's board over to next's board, so that swapping in a simulator
+// loaded from a snapshot or replay doesn't silently disconnect subscribers.
+func (s *Simulator) HandOver(next *Simulator) {
+	next.gameboard.subscriptions = s.gameboard.subscriptions
+}
+
+// Seed returns the seed the simulator's random source was created with.
+func (s *Simulator) Seed() int64 {
+	return s.seed
+}
+
+// Ticks returns the number of ticks that have been simulated.
+func (s *Simulator) Ticks() int {
+	return s.ticks
+}
+
+// Hours returns the amount of game time that has passed, in game hours.
+func (s *Simulator) Hours() float64 {
+	return float64(s.ticks) / 60.0 / 60.0 / 60.0
+}
+
+// Won returns true once any Winnable entity has reported a win.
+func (s *Simulator) Won() bool {
+	return s.won
+}
+
+// Trigger queues action to be seen by the entities during the next tick.
+func (s *Simulator) Trigger(action Action) {
+	s.pending[action] = true
+}
+
+// Step progresses the simulation one tick, updating all entities on the board. It returns true if a
+// Winnable entity won during this tick.
+func (s *Simulator) Step() bool {
+	s.ticks++
+
+	// the pending actions are handed to the board for this tick only, a fresh set collects the next tick's. Most
+	// ticks have none, they reuse the empty set.
+	s.gameboard.actions = nil
+	if len(s.pending) > 0 {
+		s.gameboard.actions = s.pending
+		s.pending = map[Action]bool{}
+	}
+	defer func() {
+		s.gameboard.actions = nil
+	}()
+
+	won := false
+	s.gameboard.tick(s.ticks, func(e Entity) {
+		e.Update()
+		if win, ok := e.(Winnable); ok {
+			if win.Win() {
+				won = true
+			}
+		}
+	})
+
+	if won {
+		s.won = true
+	}
+	return won
+}
+
+// Run steps the simulation n times. If stopOnWin is set, it returns early after the tick that wins.
+// The number of ticks that were run is returned.
+func (s *Simulator) Run(n int, stopOnWin bool) int {
+	for i := 0; i < n; i++ {
+		if s.Step() && stopOnWin {
+			return i + 1
+		}
+	}
+	return n
+}
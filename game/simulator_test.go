@@ -0,0 +1,59 @@
+package game_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"github.com/tannerhat/Cactus-Simulator/nature"
+)
+
+// grownTicks is how long the benchmarks' world runs before it is timed, long enough for roots, water and clouds to
+// be spread over the board the way they are for most of a game
+const grownTicks = 20000
+
+// stepsPerWorld is how many ticks BenchmarkStep times from the grown world before starting over from it, so a long run
+// doesn't drift into a busier or quieter part of the game
+const stepsPerWorld = 1000
+
+// grown holds a snapshot of the default world after grownTicks, it is made by the first benchmark that needs it
+var grown []byte
+
+// grownWorld returns the default world as it is grownTicks into a game
+func grownWorld(b *testing.B) *game.Simulator {
+	b.Helper()
+	if grown == nil {
+		sim := newWorld(b, nature.DefaultConfig(), 1)
+		sim.Run(grownTicks, false)
+		grown = save(b, sim)
+	}
+	sim, err := game.LoadSimulator(bytes.NewReader(grown))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return sim
+}
+
+func BenchmarkStep(b *testing.B) {
+	sim := grownWorld(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i > 0 && i%stepsPerWorld == 0 {
+			b.StopTimer()
+			sim = grownWorld(b)
+			b.StartTimer()
+		}
+		sim.Step()
+	}
+}
+
+func BenchmarkAppendEntities(b *testing.B) {
+	g := grownWorld(b).Gameboard()
+	entities := g.AppendEntities(nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entities = g.AppendEntities(entities[:0])
+	}
+}
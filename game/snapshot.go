@@ -0,0 +1,283 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math/rand"
+	"sort"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Save. Snapshots with a different version are
+// rejected when loading.
+const SnapshotVersion = 7
+
+// Snapshotter is implemented by entities that can be saved in a snapshot. The entity's type must also be registered
+// with RegisterKind so that it can be created again when the snapshot is loaded.
+type Snapshotter interface {
+	Entity
+
+	// Kind returns the name the entity's type is registered under
+	Kind() string
+
+	// Snapshot returns the entity's state in a form that can be encoded as JSON. Other entities it refers to must be
+	// stored using refs.
+	Snapshot(refs *Refs) (interface{}, error)
+
+	// Restore sets the entity's state from the data returned by Snapshot. It is called instead of AddToBoard, the
+	// gameboard's cells are restored by the snapshot so the entity should only keep a reference to gameboard.
+	Restore(data json.RawMessage, refs *Refs, gameboard Gameboard) error
+}
+
+// Refs converts between entities and the IDs used to refer to them within a snapshot.
+type Refs struct {
+	ids      map[Entity]EntityID
+	entities map[EntityID]Entity
+}
+
+// Ref returns the ID that refers to e. A nil entity is stored as NoEntity.
+func (r *Refs) Ref(e Entity) (EntityID, error) {
+	if e == nil {
+		return NoEntity, nil
+	}
+	id, ok := r.ids[e]
+	if !ok {
+		return NoEntity, fmt.Errorf("%T is not on the board, it can't be referred to in a snapshot", e)
+	}
+	return id, nil
+}
+
+// Entity returns the entity that ref refers to. The entity may not have been restored yet.
+func (r *Refs) Entity(ref EntityID) (Entity, error) {
+	if ref == NoEntity {
+		return nil, nil
+	}
+	e, ok := r.entities[ref]
+	if !ok {
+		return nil, fmt.Errorf("snapshot refers to entity %d but doesn't contain it", ref)
+	}
+	return e, nil
+}
+
+var kinds = map[string]func() Snapshotter{}
+
+// RegisterKind makes the entity type named kind loadable from snapshots, create must return a new empty entity
+// that Restore will be called on.
+func RegisterKind(kind string, create func() Snapshotter) {
+	if _, ok := kinds[kind]; ok {
+		panic(fmt.Sprintf("entity kind %q registered twice", kind))
+	}
+	kinds[kind] = create
+}
+
+type snapshot struct {
+	Version int
+	Width   int
+	Height  int
+	// Boundaries holds the boundary of each edge, in Edge order
+	Boundaries []Boundary
+	Seed       int64
+	RandState  uint64
+	Ticks      int
+	Won        bool
+	// Pending holds the actions triggered for the next tick, in order
+	Pending []Action
+	// NextID is the ID the next entity added will get
+	NextID   EntityID
+	Entities []entitySnapshot
+	// Board holds the entity ref at each cell of each layer, the cells of a layer are stored column by column
+	Board [][]EntityID
+}
+
+type entitySnapshot struct {
+	ID    EntityID
+	Kind  string
+	State json.RawMessage
+}
+
+// Save writes the full state of the simulation to w. Every entity on the board must be a Snapshotter.
+func (s *Simulator) Save(w io.Writer) error {
+	g := s.gameboard
+
+	refs := &Refs{ids: g.ids}
+	width, height := g.Size()
+	snap := snapshot{
+		Version:    SnapshotVersion,
+		Width:      width,
+		Height:     height,
+		Boundaries: g.boundaries[:],
+		Seed:       s.seed,
+		RandState:  g.source.state,
+		Ticks:      s.ticks,
+		Won:        s.won,
+		Pending:    make([]Action, 0, len(s.pending)),
+		NextID:     g.nextID,
+		Entities:   make([]entitySnapshot, 0, len(g.entities)-g.removed),
+		Board:      make([][]EntityID, cellLayerCount),
+	}
+
+	for action := range s.pending {
+		snap.Pending = append(snap.Pending, action)
+	}
+	sort.Slice(snap.Pending, func(i, j int) bool { return snap.Pending[i] < snap.Pending[j] })
+
+	for _, e := range g.entities {
+		if e == nil {
+			continue
+		}
+		snapshotter, ok := e.(Snapshotter)
+		if !ok {
+			return fmt.Errorf("%T can't be saved in a snapshot", e)
+		}
+		state, err := snapshotter.Snapshot(refs)
+		if err != nil {
+			return fmt.Errorf("saving %s: %v", snapshotter.Kind(), err)
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("saving %s: %v", snapshotter.Kind(), err)
+		}
+		snap.Entities = append(snap.Entities, entitySnapshot{ID: g.ids[e], Kind: snapshotter.Kind(), State: data})
+	}
+
+	for layer := range snap.Board {
+		snap.Board[layer] = make([]EntityID, 0, width*height)
+		for x := range g.board {
+			for y := range g.board[x] {
+				ref, err := refs.Ref(g.board[x][y][layer])
+				if err != nil {
+					return fmt.Errorf("saving cell (%d,%d) layer %d: %v", x, y, layer, err)
+				}
+				snap.Board[layer] = append(snap.Board[layer], ref)
+			}
+		}
+	}
+
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// LoadSimulator reads a snapshot written by Save and returns a simulator that continues exactly where the saved one
+// left off.
+func LoadSimulator(r io.Reader) (*Simulator, error) {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("reading snapshot: %v", err)
+	}
+	if snap.Version != SnapshotVersion {
+		return nil, fmt.Errorf("snapshot is version %d, only version %d is supported", snap.Version, SnapshotVersion)
+	}
+	if snap.Width <= 0 || snap.Height <= 0 {
+		return nil, fmt.Errorf("snapshot has an invalid board size %dx%d", snap.Width, snap.Height)
+	}
+	if len(snap.Boundaries) != int(edgeCount) {
+		return nil, fmt.Errorf("snapshot has %d boundaries, expected %d", len(snap.Boundaries), edgeCount)
+	}
+	if len(snap.Board) != int(cellLayerCount) {
+		return nil, fmt.Errorf("snapshot board has %d layers, expected %d", len(snap.Board), cellLayerCount)
+	}
+	for layer := range snap.Board {
+		if len(snap.Board[layer]) != snap.Width*snap.Height {
+			return nil, fmt.Errorf("snapshot board layer %d has %d cells, expected %d", layer, len(snap.Board[layer]), snap.Width*snap.Height)
+		}
+	}
+
+	g := &gameboard{
+		ids:    make(map[Entity]EntityID, len(snap.Entities)),
+		slots:  make(map[EntityID]int, len(snap.Entities)),
+		timers: map[EntityID][]timer{},
+		nextID: snap.NextID,
+		board:  make([][][cellLayerCount]Entity, snap.Width),
+		source: &source{state: snap.RandState},
+	}
+	g.rand = rand.New(g.source)
+	for i := range g.board {
+		g.board[i] = make([][cellLayerCount]Entity, snap.Height)
+	}
+	copy(g.boundaries[:], snap.Boundaries)
+
+	// every entity is created before any are restored so that entities can refer to ones later in the list
+	entities := make([]Entity, len(snap.Entities))
+	refs := &Refs{ids: g.ids, entities: make(map[EntityID]Entity, len(snap.Entities))}
+	for i, es := range snap.Entities {
+		create, ok := kinds[es.Kind]
+		if !ok {
+			return nil, fmt.Errorf("snapshot contains unknown entity kind %q", es.Kind)
+		}
+		if es.ID == NoEntity || es.ID >= snap.NextID {
+			return nil, fmt.Errorf("snapshot entity %d has an invalid ID %d", i, es.ID)
+		}
+		if _, ok := refs.entities[es.ID]; ok {
+			return nil, fmt.Errorf("snapshot has more than one entity with ID %d", es.ID)
+		}
+		entities[i] = create()
+		refs.entities[es.ID] = entities[i]
+		g.ids[entities[i]] = es.ID
+		g.slots[es.ID] = i
+	}
+
+	for i, es := range snap.Entities {
+		if err := entities[i].(Snapshotter).Restore(es.State, refs, g); err != nil {
+			return nil, fmt.Errorf("restoring %s: %v", es.Kind, err)
+		}
+	}
+	g.entities = entities
+
+	for layer := range snap.Board {
+		for i, ref := range snap.Board[layer] {
+			e, err := refs.Entity(ref)
+			if err != nil {
+				return nil, err
+			}
+			g.board[i/snap.Height][i%snap.Height][layer] = e
+		}
+	}
+
+	s := &Simulator{
+		gameboard: g,
+		seed:      snap.Seed,
+		ticks:     snap.Ticks,
+		won:       snap.Won,
+		pending:   map[Action]bool{},
+	}
+	for _, action := range snap.Pending {
+		s.pending[action] = true
+	}
+	return s, nil
+}
+
+type shapeState struct {
+	X     int
+	Y     int
+	Cells [][]bool
+	Color color.RGBA
+	Layer int
+}
+
+// MarshalJSON stores everything about the shape except its gameboard so it can be used in entity snapshots.
+func (s *Shape) MarshalJSON() ([]byte, error) {
+	return json.Marshal(shapeState{
+		X:     s.X,
+		Y:     s.Y,
+		Cells: s.Cells,
+		Color: color.RGBAModel.Convert(s.color).(color.RGBA),
+		Layer: s.layer,
+	})
+}
+
+// UnmarshalJSON restores a shape written by MarshalJSON, the gameboard must be set separately.
+func (s *Shape) UnmarshalJSON(data []byte) error {
+	var state shapeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if len(state.Cells) == 0 || len(state.Cells[0]) == 0 {
+		return fmt.Errorf("shape has no cells")
+	}
+	s.X = state.X
+	s.Y = state.Y
+	s.Cells = state.Cells
+	s.color = state.Color
+	s.layer = state.Layer
+	return nil
+}
@@ -0,0 +1,62 @@
+package game_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"github.com/tannerhat/Cactus-Simulator/nature"
+)
+
+func TestSnapshotContinuesExactly(t *testing.T) {
+	sim := newWorld(t, stormyConfig(), 7)
+	sim.Run(3000, false)
+	// tools leave entities and cells the scenario never starts with
+	for _, use := range []game.ToolUse{
+		{Tool: "place rock", X: 10, Y: 20},
+		{Tool: "place rock", X: 11, Y: 20},
+		{Tool: "dig", X: 40, Y: 36},
+		{Tool: "seed cloud", X: 20, Y: 5},
+	} {
+		if ok, err := sim.UseTool(use); !ok || err != nil {
+			t.Fatalf("%s at (%d,%d) returned %t, %v", use.Tool, use.X, use.Y, ok, err)
+		}
+	}
+	sim.Trigger(game.ActionAbsorb)
+
+	saved := save(t, sim)
+	loaded, err := game.LoadSimulator(bytes.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(save(t, loaded), saved) {
+		t.Fatal("saving the loaded world gives a different snapshot")
+	}
+
+	if loaded.Ticks() != sim.Ticks() {
+		t.Errorf("the loaded world is at tick %d, want %d", loaded.Ticks(), sim.Ticks())
+	}
+	rocks := game.OfType(loaded.Gameboard(), (*nature.Rock)(nil))
+	if len(rocks) != 1 || groundAt(loaded, 10, 20) != rocks[0] || groundAt(loaded, 11, 20) != rocks[0] {
+		t.Errorf("the loaded world has %d rocks, want one covering (10,20) and (11,20)", len(rocks))
+	}
+	if e := groundAt(loaded, 40, 36); e != nil {
+		t.Errorf("the dug cell (40,36) holds %T in the loaded world", e)
+	}
+	if got, want := len(game.OfType(loaded.Gameboard(), (*nature.Cloud)(nil))), len(game.OfType(sim.Gameboard(), (*nature.Cloud)(nil))); got != want {
+		t.Errorf("the loaded world has %d clouds, want %d", got, want)
+	}
+	// the loaded roots carry on growing on the loaded board
+	roots := game.OfType(loaded.Gameboard(), (*nature.Roots)(nil))[0]
+	grew := false
+	loaded.Gameboard().Subscribe(func(e game.Event) {
+		if e, ok := e.(nature.RootGrown); ok && e.Roots == roots {
+			grew = true
+		}
+	})
+
+	runInStep(t, sim, loaded, 4, 1000)
+	if !grew {
+		t.Error("the loaded world's roots never grew")
+	}
+}
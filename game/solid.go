@@ -4,7 +4,7 @@ import (
 	"image/color"
 )
 
-// Solid is an extension of Shape with a physical presence on the board
+// Solid is an extension of Shape with a physical presence in the ground layer of the board
 type Solid struct {
 	*Shape
 }
@@ -23,7 +23,7 @@ func (s *Solid) AddToBoard(gameboard Gameboard) {
 	for x := range s.Cells {
 		for y := range s.Cells[x] {
 			if s.Cells[x][y] {
-				s.Gameboard.SetEntity(s, s.X+x, s.Y+y)
+				s.Gameboard.SetEntity(GroundLayer, s, s.X+x, s.Y+y)
 			}
 		}
 	}
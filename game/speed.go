@@ -0,0 +1,35 @@
+package game
+
+// Speeds are the numbers of ticks per frame that the speed actions choose between, slowest first.
+var Speeds = []int{0, 1, 10, 60, 300}
+
+// ApplySpeed returns the speed that action changes current to. It returns false if action doesn't change the speed.
+func ApplySpeed(action Action, current int) (int, bool) {
+	switch action {
+	case ActionPause:
+		return Speeds[0], true
+	case ActionSpeed1:
+		return Speeds[1], true
+	case ActionSpeed2:
+		return Speeds[2], true
+	case ActionSpeed3:
+		return Speeds[3], true
+	case ActionSpeed4:
+		return Speeds[4], true
+	case ActionSpeedUp:
+		for _, speed := range Speeds {
+			if speed > current {
+				return speed, true
+			}
+		}
+		return current, true
+	case ActionSpeedDown:
+		for i := len(Speeds) - 1; i >= 0; i-- {
+			if Speeds[i] < current {
+				return Speeds[i], true
+			}
+		}
+		return current, true
+	}
+	return current, false
+}
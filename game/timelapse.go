@@ -0,0 +1,195 @@
+package game
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// FrameWriter receives the frames of a timelapse.
+type FrameWriter interface {
+	// WriteFrame stores the next frame, img is reused for the frame after so it must not be kept
+	WriteFrame(img *image.RGBA) error
+
+	// Close finishes the output once the last frame has been written
+	Close() error
+}
+
+// Timelapse draws the board every few ticks and passes the frames to a FrameWriter.
+type Timelapse struct {
+	every    int
+	shrink   int
+	renderer *ImageRenderer
+	small    *image.RGBA
+	out      FrameWriter
+	frames   int
+}
+
+// NewTimelapse returns a timelapse that captures a frame of a width x height board every `every` ticks. Frames are
+// drawn one pixel per cell and then scaled down by averaging shrink x shrink blocks of cells, a shrink of 1 keeps
+// every cell.
+func NewTimelapse(width int, height int, every int, shrink int, out FrameWriter) (*Timelapse, error) {
+	if every < 1 {
+		return nil, fmt.Errorf("timelapse must capture at least every tick, got every %d", every)
+	}
+	if shrink < 1 || shrink > width || shrink > height {
+		return nil, fmt.Errorf("can't shrink a %dx%d board by %d", width, height, shrink)
+	}
+	t := &Timelapse{
+		every:    every,
+		shrink:   shrink,
+		renderer: NewImageRenderer(width, height, 1),
+		out:      out,
+	}
+	if shrink > 1 {
+		t.small = image.NewRGBA(image.Rect(0, 0, width/shrink, height/shrink))
+	}
+	return t, nil
+}
+
+// Capture draws a frame of sim's board if its current tick is one the timelapse captures. It should be called after
+// each step.
+func (t *Timelapse) Capture(sim *Simulator) error {
+	if !due(sim.Ticks(), t.every, 0) {
+		return nil
+	}
+	return t.CaptureNow(sim.Gameboard())
+}
+
+// CaptureNow draws a frame of g regardless of the tick.
+func (t *Timelapse) CaptureNow(g Gameboard) error {
+	t.renderer.Clear()
+	DrawBoard(t.renderer, g)
+	img := t.renderer.Image()
+	if t.small != nil {
+		shrinkImage(t.small, img, t.shrink)
+		img = t.small
+	}
+	t.frames++
+	return t.out.WriteFrame(img)
+}
+
+// Frames returns the number of frames captured so far.
+func (t *Timelapse) Frames() int {
+	return t.frames
+}
+
+// Close finishes the timelapse's output.
+func (t *Timelapse) Close() error {
+	return t.out.Close()
+}
+
+// shrinkImage fills dst with the average color of each n x n block of src
+func shrinkImage(dst *image.RGBA, src *image.RGBA, n int) {
+	bounds := dst.Bounds()
+	count := uint32(n * n)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			var r, g, b, a uint32
+			for sy := 0; sy < n; sy++ {
+				for sx := 0; sx < n; sx++ {
+					c := src.RGBAAt(x*n+sx, y*n+sy)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					b += uint32(c.B)
+					a += uint32(c.A)
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / count), uint8(g / count), uint8(b / count), uint8(a / count)})
+		}
+	}
+}
+
+// PNGSequence writes each frame to its own numbered PNG file in a directory.
+type PNGSequence struct {
+	dir    string
+	frames int
+}
+
+// NewPNGSequence returns a FrameWriter that writes frame-00000.png, frame-00001.png and so on into dir, creating it
+// if needed.
+func NewPNGSequence(dir string) (*PNGSequence, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &PNGSequence{dir: dir}, nil
+}
+
+func (p *PNGSequence) WriteFrame(img *image.RGBA) error {
+	f, err := os.Create(filepath.Join(p.dir, fmt.Sprintf("frame-%05d.png", p.frames)))
+	if err != nil {
+		return err
+	}
+	p.frames++
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (p *PNGSequence) Close() error {
+	return nil
+}
+
+// MaxGIFFrames is the most frames a GIFWriter holds. The frames are kept in memory until the GIF is written, longer
+// timelapses should be written as PNGs.
+const MaxGIFFrames = 2000
+
+// GIFWriter collects frames into an animated GIF that is written out when it is closed. It holds at most MaxGIFFrames
+// frames.
+type GIFWriter struct {
+	w     io.WriteCloser
+	delay int
+	anim  gif.GIF
+}
+
+// NewGIFWriter returns a FrameWriter that writes an animated GIF to w, showing each frame for delay hundredths of a
+// second. w is closed along with the writer.
+func NewGIFWriter(w io.WriteCloser, delay int) *GIFWriter {
+	return &GIFWriter{w: w, delay: delay}
+}
+
+func (g *GIFWriter) WriteFrame(img *image.RGBA) error {
+	if len(g.anim.Image) >= MaxGIFFrames {
+		return fmt.Errorf("a timelapse GIF holds at most %d frames, write longer timelapses to a directory of PNGs", MaxGIFFrames)
+	}
+	// frames are mapped to the nearest color without dithering, dithering makes still areas flicker between frames
+	frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.Draw(frame, frame.Bounds(), img, img.Bounds().Min, draw.Src)
+	g.anim.Image = append(g.anim.Image, frame)
+	g.anim.Delay = append(g.anim.Delay, g.delay)
+	return nil
+}
+
+func (g *GIFWriter) Close() error {
+	if len(g.anim.Image) == 0 {
+		g.w.Close()
+		return fmt.Errorf("timelapse has no frames")
+	}
+	if err := gif.EncodeAll(g.w, &g.anim); err != nil {
+		g.w.Close()
+		return err
+	}
+	return g.w.Close()
+}
+
+// CreateTimelapseOutput returns a FrameWriter for path, an animated GIF if it ends in .gif and otherwise a directory
+// of PNGs.
+func CreateTimelapseOutput(path string) (FrameWriter, error) {
+	if filepath.Ext(path) != ".gif" {
+		return NewPNGSequence(path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewGIFWriter(f, 5), nil
+}
@@ -0,0 +1,46 @@
+package game_test
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"strings"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// closeBuffer is a bytes.Buffer that can be closed
+type closeBuffer struct {
+	bytes.Buffer
+}
+
+func (b *closeBuffer) Close() error {
+	return nil
+}
+
+func TestGIFWriterStopsAtMaxFrames(t *testing.T) {
+	var buf closeBuffer
+	w := game.NewGIFWriter(&buf, 5)
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i := 0; i < game.MaxGIFFrames; i++ {
+		if err := w.WriteFrame(img); err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+	}
+	if err := w.WriteFrame(img); err == nil || !strings.Contains(err.Error(), "PNGs") {
+		t.Errorf("writing frame %d: got error %v, want one pointing at PNGs", game.MaxGIFFrames, err)
+	}
+
+	// the frames written before the limit are still saved
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != game.MaxGIFFrames {
+		t.Errorf("the GIF has %d frames, want %d", len(anim.Image), game.MaxGIFFrames)
+	}
+}
@@ -0,0 +1,50 @@
+package game
+
+import (
+	"fmt"
+)
+
+// ToolFunc uses a tool on the cell (x,y) of g, which is on the board. It returns false if the tool can't be used
+// there, leaving the board as it was.
+type ToolFunc func(g Gameboard, x int, y int) bool
+
+// ToolUse is a use of a tool on a cell of the board, as recorded in a replay.
+type ToolUse struct {
+	Tool string
+	X    int
+	Y    int
+}
+
+var (
+	tools = map[string]ToolFunc{}
+	// toolNames holds the names of the tools in the order they were registered
+	toolNames []string
+)
+
+// RegisterTool makes a tool the player can use on the board with the mouse. Tools are offered in the order they are
+// registered.
+func RegisterTool(name string, use ToolFunc) {
+	if _, ok := tools[name]; ok {
+		panic(fmt.Sprintf("tool %q registered twice", name))
+	}
+	tools[name] = use
+	toolNames = append(toolNames, name)
+}
+
+// Tools returns the names of the registered tools.
+func Tools() []string {
+	return append([]string(nil), toolNames...)
+}
+
+// UseTool uses the tool on the board right away, between ticks. It returns false if the cell is off the board or the
+// tool can't be used there.
+func (s *Simulator) UseTool(use ToolUse) (bool, error) {
+	tool, ok := tools[use.Tool]
+	if !ok {
+		return false, fmt.Errorf("unknown tool %q", use.Tool)
+	}
+	if !s.gameboard.InBounds(use.X, use.Y) {
+		return false, nil
+	}
+	return tool(s.gameboard, use.X, use.Y), nil
+}
@@ -1,22 +1,25 @@
 package nature
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
-	"math/rand"
 
 	"github.com/tannerhat/Cactus-Simulator/game"
 )
 
-// Cloud is a Shape that creates water entities that fall starting from the cloud's lower edge. Raining can be toggled by spacebar.
+// Cloud is a Shape that rains water that falls starting from the cloud's lower edge. Raining can be toggled by spacebar.
 type Cloud struct {
 	*game.Shape
 	rate    int
 	ticks   int
 	raining bool
+	// water is the field the cloud rains into, it is looked up on the first drop
+	water *WaterField
 }
 
 // NewCloud returns a cloud that will be at gameboard coordinates (x,y) once added to the game. Rate indicates
-// how many ticks between each water entity creation.
+// how many ticks between each drop of rain.
 func NewCloud(x int, y int, width int, height int, rate int) *Cloud {
 	c := &Cloud{
 		Shape:   game.NewShape(x, y, width, height, 0, color.RGBA{0xff, 0xff, 0xff, 0xff}),
@@ -38,24 +41,88 @@ func NewCloud(x int, y int, width int, height int, rate int) *Cloud {
 	return c
 }
 
-// Update the cloud, if it causes a water entity to be created, the cloud will add the entity to the gameboard directly.
+// AddToBoard puts the cloud in the air layer. Where it overlaps another cloud the cell keeps the one already there.
+func (c *Cloud) AddToBoard(gameboard game.Gameboard) {
+	c.Shape.AddToBoard(gameboard)
+	c.Occupy(game.AirLayer, c)
+}
+
+// drift moves the cloud one cell to the right, taking its place in the air layer with it
+func (c *Cloud) drift() {
+	c.Vacate(game.AirLayer, c)
+	c.X++
+	c.Occupy(game.AirLayer, c)
+}
+
+// Update the cloud, while it is raining it pours a drop of water into the board's water field just under the cloud
+// once every rate ticks. A board without a water field gets no rain.
 func (c *Cloud) Update() {
-	if c.raining {
-		if c.ticks%c.rate == 0 {
-			c.Gameboard.AddEntity(
-				&Water{
-					x:       rand.Intn(c.Width()-2) + c.X + 1, // because the edges are rounded
-					y:       c.Y + c.Height(),
-					density: 1,
-					settled: 0,
-				},
-			)
+	if !c.raining {
+		return
+	}
+	drop := c.ticks%c.rate == 0
+	c.ticks++
+	if !drop {
+		return
+	}
+	if c.water == nil {
+		fields := game.OfType(c.Gameboard, (*WaterField)(nil))
+		if len(fields) == 0 {
+			return
 		}
-		c.ticks++
+		c.water = fields[0].(*WaterField)
 	}
+
+	x := c.Gameboard.Rand().Intn(c.Width()-2) + c.X + 1 // because the edges are rounded
+	y := c.Y + c.Height()
+	// rain can't start inside the ground, the field leaves those drops out
+	c.water.AddWater(x, y, 1)
 }
 
 func (c *Cloud) SetStatus(raining bool, rate int) {
 	c.raining = raining
 	c.rate = rate
 }
+
+type cloudState struct {
+	Shape   *game.Shape
+	Rate    int
+	Ticks   int
+	Raining bool
+}
+
+func (c *Cloud) Kind() string {
+	return "cloud"
+}
+
+func (c *Cloud) Snapshot(refs *game.Refs) (interface{}, error) {
+	return cloudState{
+		Shape:   c.Shape,
+		Rate:    c.rate,
+		Ticks:   c.ticks,
+		Raining: c.raining,
+	}, nil
+}
+
+func (c *Cloud) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state cloudState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Shape == nil {
+		return fmt.Errorf("cloud has no shape")
+	}
+	// rain falls from between the cloud's rounded edges
+	if state.Shape.Width() < 3 {
+		return fmt.Errorf("cloud must be at least 3 cells wide, got %d", state.Shape.Width())
+	}
+	if err := checkSettings("cloud ", setting{"rate", int64(state.Rate)}); err != nil {
+		return err
+	}
+	state.Shape.Gameboard = gameboard
+	c.Shape = state.Shape
+	c.rate = state.Rate
+	c.ticks = state.Ticks
+	c.raining = state.Raining
+	return nil
+}
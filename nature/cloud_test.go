@@ -0,0 +1,43 @@
+package nature
+
+import (
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+func TestCloudsOccupyAir(t *testing.T) {
+	sim := newWorld(t, smallScenario(2*MinWidth, MinHeight), stormyConfig(), 1)
+	g := sim.Gameboard()
+	width, height := g.Size()
+	for tick := 0; tick < 2000; tick++ {
+		sim.Step()
+		clouds := game.OfType(g, (*Cloud)(nil))
+		for x := 0; x < width; x++ {
+			for y := 0; y < height; y++ {
+				var covering []game.Entity
+				for _, c := range clouds {
+					if covers(c.(*Cloud).Shape, x, y) {
+						covering = append(covering, c)
+					}
+				}
+				air := g.EntityOn(game.AirLayer, x, y)
+				if len(covering) == 0 && air != nil {
+					t.Fatalf("tick %d: (%d,%d) holds %T in the air but no cloud covers it", sim.Ticks(), x, y, air)
+				}
+				if len(covering) > 0 && !containsEntity(covering, air) {
+					t.Fatalf("tick %d: (%d,%d) is covered by %d clouds but holds %v in the air", sim.Ticks(), x, y, len(covering), air)
+				}
+			}
+		}
+	}
+}
+
+func containsEntity(entities []game.Entity, e game.Entity) bool {
+	for _, o := range entities {
+		if o == e {
+			return true
+		}
+	}
+	return false
+}
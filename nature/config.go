@@ -0,0 +1,173 @@
+package nature
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Config holds the balance of the nature entities. New entities take their settings from it, entities restored from
+// a snapshot keep the settings they were saved with.
+type Config struct {
+	Soil    SoilConfig    `json:"soil"`
+	Roots   RootsConfig   `json:"roots"`
+	Plant   PlantConfig   `json:"plant"`
+	Weather WeatherConfig `json:"weather"`
+	Water   WaterConfig   `json:"water"`
+}
+
+type SoilConfig struct {
+	// AbsorbRate is the chance, one in AbsorbRate, that water moves between neighboring cells or soaks in from above
+	AbsorbRate int `json:"absorbRate"`
+	// EvaporateRate is the chance, one in EvaporateRate, that a damp cell at the surface dries out a little each tick.
+	// Deeper cells dry out more slowly.
+	EvaporateRate int `json:"evaporateRate"`
+	// MaxWetness is the most water a cell of soil holds before it stops soaking up more
+	MaxWetness uint32 `json:"maxWetness"`
+}
+
+type RootsConfig struct {
+	// GrowRate is the average number of ticks between a root cell's attempts to grow
+	GrowRate int `json:"growRate"`
+	// Speed is the number of ticks between the roots' attempts to grow. Each attempt is Speed times as likely to
+	// succeed, so a higher speed grows the roots about as quickly with fewer attempts.
+	Speed int `json:"speed"`
+	// MaxWetness is the most water a root cell holds
+	MaxWetness uint32 `json:"maxWetness"`
+}
+
+type PlantConfig struct {
+	// Speed is the number of ticks between the plant drinking from its roots
+	Speed int `json:"speed"`
+	// WaterCostPerCell is the water the plant needs for every cell it grows
+	WaterCostPerCell uint32 `json:"waterCostPerCell"`
+	// WinHeight is the height the plant has to grow past to win
+	WinHeight int `json:"winHeight"`
+}
+
+type WeatherConfig struct {
+	// CloudSpawn is the chance, one in CloudSpawn, of a cloud forming each tick when the sky is clear. It also sets how
+	// quickly clouds drift across.
+	CloudSpawn int `json:"cloudSpawn"`
+	// RainStart is the chance, one in RainStart, of a single cloud starting to rain each tick
+	RainStart int `json:"rainStart"`
+	// RainStop is the chance, one in RainStop, of the rain stopping each tick
+	RainStop int `json:"rainStop"`
+	// RainIntensity is the number of ticks between drops from a raining cloud
+	RainIntensity int `json:"rainIntensity"`
+}
+
+type WaterConfig struct {
+	// MaxDensity is the most water that can pile up in a single cell
+	MaxDensity int `json:"maxDensity"`
+}
+
+// DefaultConfig returns the balance the game was designed with.
+func DefaultConfig() Config {
+	return Config{
+		Soil: SoilConfig{
+			AbsorbRate:    3,
+			EvaporateRate: 200,
+			MaxWetness:    3,
+		},
+		Roots: RootsConfig{
+			GrowRate:   1500,
+			Speed:      1,
+			MaxWetness: 3,
+		},
+		Plant: PlantConfig{
+			Speed:            2,
+			WaterCostPerCell: 800,
+			WinHeight:        10,
+		},
+		Weather: WeatherConfig{
+			CloudSpawn:    1000,
+			RainStart:     20000,
+			RainStop:      3000,
+			RainIntensity: 2,
+		},
+		Water: WaterConfig{
+			MaxDensity: 300,
+		},
+	}
+}
+
+// LoadConfig reads a JSON config from r. Settings it leaves out keep their default values.
+func LoadConfig(r io.Reader) (Config, error) {
+	c := DefaultConfig()
+	decoder := json.NewDecoder(r)
+	// a misspelled setting would otherwise be silently ignored
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return Config{}, fmt.Errorf("reading config: %v", err)
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// Validate returns an error naming the first setting that is out of range.
+func (c Config) Validate() error {
+	for _, err := range []error{
+		c.Soil.validate("soil."),
+		c.Roots.validate("roots."),
+		c.Plant.validate("plant."),
+		c.Weather.validate("weather."),
+		c.Water.validate("water."),
+	} {
+		if err != nil {
+			return fmt.Errorf("config: %v", err)
+		}
+	}
+	return nil
+}
+
+func (c SoilConfig) validate(prefix string) error {
+	return checkSettings(prefix,
+		setting{"absorbRate", int64(c.AbsorbRate)},
+		setting{"evaporateRate", int64(c.EvaporateRate)},
+		setting{"maxWetness", int64(c.MaxWetness)})
+}
+
+func (c RootsConfig) validate(prefix string) error {
+	return checkSettings(prefix,
+		setting{"growRate", int64(c.GrowRate)},
+		setting{"speed", int64(c.Speed)},
+		setting{"maxWetness", int64(c.MaxWetness)})
+}
+
+func (c PlantConfig) validate(prefix string) error {
+	return checkSettings(prefix,
+		setting{"speed", int64(c.Speed)},
+		setting{"waterCostPerCell", int64(c.WaterCostPerCell)},
+		setting{"winHeight", int64(c.WinHeight)})
+}
+
+func (c WeatherConfig) validate(prefix string) error {
+	return checkSettings(prefix,
+		setting{"cloudSpawn", int64(c.CloudSpawn)},
+		setting{"rainStart", int64(c.RainStart)},
+		setting{"rainStop", int64(c.RainStop)},
+		setting{"rainIntensity", int64(c.RainIntensity)})
+}
+
+func (c WaterConfig) validate(prefix string) error {
+	return checkSettings(prefix, setting{"maxDensity", int64(c.MaxDensity)})
+}
+
+type setting struct {
+	name  string
+	value int64
+}
+
+// checkSettings returns an error naming the first of settings that is below 1. Every setting is either a chance of one
+// in the value, a number of ticks or an amount, none of which make sense below 1.
+func checkSettings(prefix string, settings ...setting) error {
+	for _, s := range settings {
+		if s.value < 1 {
+			return fmt.Errorf("%s%s must be at least 1, got %d", prefix, s.name, s.value)
+		}
+	}
+	return nil
+}
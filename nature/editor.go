@@ -0,0 +1,198 @@
+package nature
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// rootsBoxColor outlines the box the roots can grow in while a layout is edited
+var rootsBoxColor = color.RGBA{0xff, 0xff, 0xff, 0x60}
+
+// Editor changes a layout with the mouse tools of the window's edit mode, and builds the world it describes when the
+// game is played from it.
+type Editor struct {
+	layout *Layout
+	config Config
+	tools  []editTool
+	// buffer is the picture of the layout's cells, cells are redrawn as they are painted
+	buffer     *game.CellBuffer
+	soilColors []color.RGBA
+}
+
+// editTool is one of the editor's tools, paint uses it on the cell at as part of a drag that started at start
+type editTool struct {
+	name  string
+	paint func(start image.Point, at image.Point)
+}
+
+// NewEditor edits layout, the tools and the world it builds use the settings in config.
+func NewEditor(layout *Layout, config Config) *Editor {
+	e := &Editor{
+		layout:     layout,
+		config:     config,
+		buffer:     game.NewCellBuffer(0, 0, layout.Width, layout.Height),
+		soilColors: soilColors(config.Soil.MaxWetness),
+	}
+
+	e.tools = append(e.tools, editTool{"soil", e.paintGround(LayoutCell{Fill: FillSoil})})
+	// scenarios hold soil wetness as a single digit
+	for wetness := uint32(1); wetness <= config.Soil.MaxWetness && wetness <= 9; wetness++ {
+		e.tools = append(e.tools, editTool{fmt.Sprintf("wet soil %d", wetness), e.paintGround(LayoutCell{Fill: FillSoil, Wetness: wetness})})
+	}
+	e.tools = append(e.tools,
+		editTool{"rock", e.paintGround(LayoutCell{Fill: FillRock})},
+		editTool{"water", e.pour},
+		editTool{"air", e.paintGround(LayoutCell{Fill: FillAir})},
+		editTool{"plant", e.placePlant},
+		editTool{"root start", e.placeRootStart},
+		editTool{"roots box", e.sizeRoots},
+	)
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			e.redraw(x, y)
+		}
+	}
+	return e
+}
+
+// Tools returns the names of the editor's tools, in the order they are numbered for Paint.
+func (e *Editor) Tools() []string {
+	names := make([]string, len(e.tools))
+	for i, t := range e.tools {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Paint uses tool on the cell at, as part of a drag that started on the cell start. Cells off the board are ignored.
+func (e *Editor) Paint(tool int, start image.Point, at image.Point) {
+	if tool < 0 || tool >= len(e.tools) || !e.layout.contains(at.X, at.Y) {
+		return
+	}
+	e.tools[tool].paint(start, at)
+}
+
+// paintGround returns a tool that fills cells with c. The plant's cell is left alone so the plant is never buried.
+func (e *Editor) paintGround(c LayoutCell) func(image.Point, image.Point) {
+	return func(start image.Point, at image.Point) {
+		if plant, ok := e.layout.Plant(); ok && plant == at && c.Fill != FillAir {
+			return
+		}
+		e.layout.Set(at.X, at.Y, c)
+		e.redraw(at.X, at.Y)
+	}
+}
+
+// pour adds a unit of water to the cell, up to the most a cell can hold. Holding the tool still piles water up.
+func (e *Editor) pour(start image.Point, at image.Point) {
+	c := e.layout.At(at.X, at.Y)
+	switch c.Fill {
+	case FillWater:
+		if c.Volume < e.config.Water.MaxDensity {
+			c.Volume++
+		}
+	case FillAir:
+		c = LayoutCell{Fill: FillWater, Volume: 1}
+	default:
+		// water can't start in the ground
+		return
+	}
+	e.layout.Set(at.X, at.Y, c)
+	e.redraw(at.X, at.Y)
+}
+
+// placePlant moves the plant to the cell if it isn't in the ground
+func (e *Editor) placePlant(start image.Point, at image.Point) {
+	if fill := e.layout.At(at.X, at.Y).Fill; fill == FillSoil || fill == FillRock {
+		return
+	}
+	e.layout.SetPlant(at.X, at.Y)
+}
+
+// placeRootStart moves the start of the roots to the cell if it is soil
+func (e *Editor) placeRootStart(start image.Point, at image.Point) {
+	if e.layout.At(at.X, at.Y).Fill != FillSoil {
+		return
+	}
+	e.layout.SetRootStart(at.X, at.Y)
+}
+
+// sizeRoots makes the roots' box the cells between the start of the drag and the cell
+func (e *Editor) sizeRoots(start image.Point, at image.Point) {
+	box := image.Rectangle{Min: start, Max: at}.Canon()
+	box.Max = box.Max.Add(image.Point{1, 1})
+	e.layout.SetRoots(box)
+}
+
+// redraw colors the cell (x,y) of the editor's picture
+func (e *Editor) redraw(x int, y int) {
+	switch c := e.layout.At(x, y); c.Fill {
+	case FillSoil:
+		wetness := c.Wetness
+		if wetness >= uint32(len(e.soilColors)) {
+			wetness = uint32(len(e.soilColors) - 1)
+		}
+		e.buffer.SetRGBA(x, y, e.soilColors[wetness])
+	case FillRock:
+		e.buffer.SetRGBA(x, y, rockColor)
+	case FillWater:
+		e.buffer.SetRGBA(x, y, waterColor)
+	default:
+		e.buffer.SetRGBA(x, y, color.RGBA{})
+	}
+}
+
+// Draw draws the layout through r, with the roots' box outlined.
+func (e *Editor) Draw(r game.Renderer) {
+	l := e.layout
+	r.FillRect(0, 0, l.Width, l.Height, clearSky)
+	r.DrawBuffer(e.buffer)
+
+	if box := l.Roots(); !box.Empty() {
+		r.FillRect(box.Min.X, box.Min.Y, box.Dx(), 1, rootsBoxColor)
+		if box.Dy() > 1 {
+			r.FillRect(box.Min.X, box.Max.Y-1, box.Dx(), 1, rootsBoxColor)
+		}
+		if box.Dy() > 2 {
+			r.FillRect(box.Min.X, box.Min.Y+1, 1, box.Dy()-2, rootsBoxColor)
+			if box.Dx() > 1 {
+				r.FillRect(box.Max.X-1, box.Min.Y+1, 1, box.Dy()-2, rootsBoxColor)
+			}
+		}
+	}
+	if start, ok := l.RootStart(); ok {
+		r.FillCell(start.X, start.Y, dryRootColor)
+	}
+	if plant, ok := l.Plant(); ok {
+		r.FillCell(plant.X, plant.Y, plantColor)
+	}
+}
+
+// Save writes the layout to w as a scenario.
+func (e *Editor) Save(w io.Writer) error {
+	s, err := e.layout.Scenario()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Build returns the entities of the world the layout describes, exactly as if it had been saved and loaded again.
+func (e *Editor) Build() ([]game.Entity, error) {
+	s, err := e.layout.Scenario()
+	if err != nil {
+		return nil, err
+	}
+	return s.Build(e.config)
+}
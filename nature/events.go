@@ -0,0 +1,33 @@
+package nature
+
+import (
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// RainStarted is published on the gameboard when the weather starts raining.
+type RainStarted struct{}
+
+// RainStopped is published on the gameboard when the weather stops raining.
+type RainStopped struct{}
+
+// WaterAbsorbed is published when water at the board cell (X,Y) is taken in by Absorber. That is either soil soaking
+// up a drop or roots drawing water out of the soil.
+type WaterAbsorbed struct {
+	Absorber game.Entity
+	X        int
+	Y        int
+}
+
+// RootGrown is published when roots grow into the board cell (X,Y).
+type RootGrown struct {
+	Roots *Roots
+	X     int
+	Y     int
+}
+
+// PlantGrew is published when a plant grows, with its new size.
+type PlantGrew struct {
+	Plant  *Plant
+	Width  int
+	Height int
+}
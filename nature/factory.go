@@ -0,0 +1,348 @@
+package nature
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// Factory creates an entity from its description in a scenario. spec holds the description's fields other than its
+// kind and name.
+type Factory func(spec json.RawMessage, b *Builder) (game.Entity, error)
+
+var factories = map[string]Factory{}
+
+// RegisterFactory makes the entity kind usable in scenarios.
+func RegisterFactory(kind string, create Factory) {
+	if _, ok := factories[kind]; ok {
+		panic(fmt.Sprintf("entity factory %q registered twice", kind))
+	}
+	factories[kind] = create
+}
+
+// Builder is what factories are given while a scenario is built. Settings a description leaves out are taken from
+// its config, and entities built earlier can be looked up by name.
+type Builder struct {
+	Config Config
+	Width  int
+	Height int
+	named  map[string]game.Entity
+	// built holds the entities built so far, in order
+	built []game.Entity
+}
+
+// Named returns the entity built earlier in the scenario under name.
+func (b *Builder) Named(name string) (game.Entity, error) {
+	e, ok := b.named[name]
+	if !ok {
+		return nil, fmt.Errorf("no entity named %q before it in the scenario", name)
+	}
+	return e, nil
+}
+
+// groundAt returns the soil or rock built earlier in the scenario that covers the board cell (x,y), or nil if neither
+// does
+func (b *Builder) groundAt(x int, y int) game.Entity {
+	for _, e := range b.built {
+		switch ground := e.(type) {
+		case *Soil:
+			if covers(ground.Shape, x, y) {
+				return ground
+			}
+		case *Rock:
+			if covers(ground.Shape, x, y) {
+				return ground
+			}
+		}
+	}
+	return nil
+}
+
+// checkOpen returns an error if soil or rock built earlier covers the board cell (x,y)
+func (b *Builder) checkOpen(x int, y int) error {
+	switch b.groundAt(x, y).(type) {
+	case *Soil:
+		return fmt.Errorf("(%d,%d) is already soil", x, y)
+	case *Rock:
+		return fmt.Errorf("(%d,%d) is already rock", x, y)
+	}
+	return nil
+}
+
+// covers returns true if the board cell (x,y) is one of the shape's cells
+func covers(s *game.Shape, x int, y int) bool {
+	x -= s.X
+	y -= s.Y
+	return x >= 0 && y >= 0 && x < s.Width() && y < s.Height() && s.Cells[x][y]
+}
+
+// checkArea returns an error if the area doesn't fit on the board
+func (b *Builder) checkArea(x int, y int, width int, height int) error {
+	if width < 1 || height < 1 {
+		return fmt.Errorf("%dx%d is not a usable size", width, height)
+	}
+	if x < 0 || y < 0 || x+width > b.Width || y+height > b.Height {
+		return fmt.Errorf("%dx%d at (%d,%d) doesn't fit on the %dx%d board", width, height, x, y, b.Width, b.Height)
+	}
+	return nil
+}
+
+func init() {
+	RegisterFactory("soil", newSoilFromSpec)
+	RegisterFactory("rock", newRockFromSpec)
+	RegisterFactory("roots", newRootsFromSpec)
+	RegisterFactory("plant", newPlantFromSpec)
+	RegisterFactory("weather", newWeatherFromSpec)
+	RegisterFactory("water", newWaterFieldFromSpec)
+}
+
+type soilSpec struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Wetness is the water every cell of the soil starts with
+	Wetness uint32 `json:"wetness"`
+	// Cells optionally gives the soil's cells as one string per row, a digit is soil with that wetness and '.' is a
+	// gap. Without it every cell of the box is soil.
+	Cells []string `json:"cells"`
+	SoilConfig
+}
+
+func newSoilFromSpec(spec json.RawMessage, b *Builder) (game.Entity, error) {
+	s := soilSpec{SoilConfig: b.Config.Soil}
+	if err := decodeSpec(spec, &s); err != nil {
+		return nil, err
+	}
+	if err := b.checkArea(s.X, s.Y, s.Width, s.Height); err != nil {
+		return nil, err
+	}
+	if err := s.validate(""); err != nil {
+		return nil, err
+	}
+	if s.Wetness > s.MaxWetness {
+		return nil, fmt.Errorf("wetness %d is more than the max wetness of %d", s.Wetness, s.MaxWetness)
+	}
+	if s.Cells != nil && s.Wetness != 0 {
+		return nil, fmt.Errorf("give either wetness or cells, not both")
+	}
+
+	soil := NewSoil(s.X, s.Y, s.Width, s.Height, s.SoilConfig)
+	soil.soak(s.Wetness)
+	if s.Cells != nil {
+		err := parseCells(s.Cells, s.Width, s.Height, func(x int, y int, c byte) error {
+			if c == '.' {
+				soil.Cells[x][y] = false
+				return nil
+			}
+			if c < '0' || c > '9' {
+				return fmt.Errorf("'%c' is neither a wetness nor '.'", c)
+			}
+			if wetness := uint32(c - '0'); wetness <= s.MaxWetness {
+				soil.wetness[x][y] = wetness
+				return nil
+			}
+			return fmt.Errorf("wetness %c is more than the max wetness of %d", c, s.MaxWetness)
+		})
+		if err != nil {
+			return nil, err
+		}
+		soil.redraw()
+	}
+	if err := checkShapeOpen(b, soil.Shape); err != nil {
+		return nil, err
+	}
+	return soil, nil
+}
+
+type rockSpec struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// Cells optionally gives the rock's cells as one string per row, '#' is rock and '.' is a gap. Without it every
+	// cell of the box is rock.
+	Cells []string `json:"cells"`
+}
+
+func newRockFromSpec(spec json.RawMessage, b *Builder) (game.Entity, error) {
+	var s rockSpec
+	if err := decodeSpec(spec, &s); err != nil {
+		return nil, err
+	}
+	if err := b.checkArea(s.X, s.Y, s.Width, s.Height); err != nil {
+		return nil, err
+	}
+
+	rock := NewRock(s.X, s.Y, s.Width, s.Height)
+	if s.Cells != nil {
+		err := parseCells(s.Cells, s.Width, s.Height, func(x int, y int, c byte) error {
+			switch c {
+			case '#':
+			case '.':
+				rock.Cells[x][y] = false
+			default:
+				return fmt.Errorf("'%c' is neither '#' nor '.'", c)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkShapeOpen(b, rock.Shape); err != nil {
+		return nil, err
+	}
+	return rock, nil
+}
+
+// checkShapeOpen returns an error if any of the shape's cells are already soil or rock
+func checkShapeOpen(b *Builder, s *game.Shape) error {
+	for x := range s.Cells {
+		for y := range s.Cells[x] {
+			if !s.Cells[x][y] {
+				continue
+			}
+			if err := b.checkOpen(s.X+x, s.Y+y); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseCells calls cell with each character of a grid given as one string per row, which must be width characters
+// across and height rows down
+func parseCells(rows []string, width int, height int, cell func(x int, y int, c byte) error) error {
+	if len(rows) != height {
+		return fmt.Errorf("cells has %d rows, not %d", len(rows), height)
+	}
+	for y, row := range rows {
+		if len(row) != width {
+			return fmt.Errorf("cells row %d is %d across, not %d", y, len(row), width)
+		}
+		for x := 0; x < width; x++ {
+			if err := cell(x, y, row[x]); err != nil {
+				return fmt.Errorf("cells (%d,%d): %v", x, y, err)
+			}
+		}
+	}
+	return nil
+}
+
+type rootsSpec struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+	// StartX and StartY are where the first root cell is, counted from the top left of the roots' area
+	StartX int `json:"startX"`
+	StartY int `json:"startY"`
+	RootsConfig
+}
+
+func newRootsFromSpec(spec json.RawMessage, b *Builder) (game.Entity, error) {
+	s := rootsSpec{RootsConfig: b.Config.Roots}
+	if err := decodeSpec(spec, &s); err != nil {
+		return nil, err
+	}
+	if err := b.checkArea(s.X, s.Y, s.Width, s.Height); err != nil {
+		return nil, err
+	}
+	if s.StartX < 0 || s.StartX >= s.Width || s.StartY < 0 || s.StartY >= s.Height {
+		return nil, fmt.Errorf("start (%d,%d) is outside the %dx%d roots", s.StartX, s.StartY, s.Width, s.Height)
+	}
+	if err := s.validate(""); err != nil {
+		return nil, err
+	}
+	if _, ok := b.groundAt(s.X+s.StartX, s.Y+s.StartY).(*Soil); !ok {
+		return nil, fmt.Errorf("start (%d,%d) isn't on soil", s.X+s.StartX, s.Y+s.StartY)
+	}
+	return NewRoots(s.X, s.Y, s.Width, s.Height, s.StartX, s.StartY, s.RootsConfig), nil
+}
+
+type plantSpec struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	// Roots is the name of the roots the plant drinks from
+	Roots string `json:"roots"`
+	PlantConfig
+}
+
+func newPlantFromSpec(spec json.RawMessage, b *Builder) (game.Entity, error) {
+	s := plantSpec{PlantConfig: b.Config.Plant}
+	if err := decodeSpec(spec, &s); err != nil {
+		return nil, err
+	}
+	if err := b.checkArea(s.X, s.Y, 1, 1); err != nil {
+		return nil, err
+	}
+	if err := s.validate(""); err != nil {
+		return nil, err
+	}
+	if err := b.checkOpen(s.X, s.Y); err != nil {
+		return nil, err
+	}
+	if s.Roots == "" {
+		return nil, fmt.Errorf("a plant needs the name of the roots it drinks from")
+	}
+	e, err := b.Named(s.Roots)
+	if err != nil {
+		return nil, err
+	}
+	roots, ok := e.(*Roots)
+	if !ok {
+		return nil, fmt.Errorf("%q is a %T, not roots", s.Roots, e)
+	}
+	return NewPlant(s.X, s.Y, roots, s.PlantConfig), nil
+}
+
+func newWeatherFromSpec(spec json.RawMessage, b *Builder) (game.Entity, error) {
+	c := b.Config.Weather
+	if err := decodeSpec(spec, &c); err != nil {
+		return nil, err
+	}
+	if err := c.validate(""); err != nil {
+		return nil, err
+	}
+	return NewWeather(c), nil
+}
+
+type waterSpec struct {
+	// Cells lists the cells that start with water in them
+	Cells []WaterCell `json:"cells"`
+	WaterConfig
+}
+
+func newWaterFieldFromSpec(spec json.RawMessage, b *Builder) (game.Entity, error) {
+	s := waterSpec{WaterConfig: b.Config.Water}
+	if err := decodeSpec(spec, &s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(""); err != nil {
+		return nil, err
+	}
+	for _, c := range s.Cells {
+		if err := b.checkArea(c.X, c.Y, 1, 1); err != nil {
+			return nil, err
+		}
+		if err := b.checkOpen(c.X, c.Y); err != nil {
+			return nil, fmt.Errorf("water can't start at %v", err)
+		}
+		if c.Volume < 1 || c.Volume > s.MaxDensity {
+			return nil, fmt.Errorf("water at (%d,%d) has volume %d, it must be from 1 to %d", c.X, c.Y, c.Volume, s.MaxDensity)
+		}
+	}
+	f := NewWaterField(s.WaterConfig)
+	f.initial = s.Cells
+	return f, nil
+}
+
+// decodeSpec decodes spec into v, a misspelled field is an error rather than being left at its default
+func decodeSpec(spec json.RawMessage, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(spec))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
@@ -0,0 +1,83 @@
+package nature
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// smallScenario is a board of width x height with the default world's layout: soil in the bottom half, roots through
+// all of it and the plant on top in the middle
+func smallScenario(width int, height int) string {
+	return fmt.Sprintf(`{
+	"width": %[1]d,
+	"height": %[2]d,
+	"scale": 5,
+	"entities": [
+		{"kind": "weather"},
+		{"kind": "soil", "x": 0, "y": %[3]d, "width": %[1]d, "height": %[4]d},
+		{"kind": "water"},
+		{"kind": "roots", "name": "roots", "x": 0, "y": %[3]d, "width": %[1]d, "height": %[4]d, "startX": %[5]d, "startY": 0},
+		{"kind": "plant", "x": %[5]d, "y": %[6]d, "roots": "roots"}
+	]
+}`, width, height, height/2, height-height/2, width/2, height/2-1)
+}
+
+// stormyConfig rains often enough that a short run has clouds, rain and wet soil
+func stormyConfig() Config {
+	config := DefaultConfig()
+	config.Weather.CloudSpawn = 100
+	config.Weather.RainStart = 10
+	return config
+}
+
+// newWorld builds the scenario onto a new simulator
+func newWorld(t testing.TB, scenario string, config Config, seed int64) *game.Simulator {
+	t.Helper()
+	s, err := LoadScenario(strings.NewReader(scenario))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities, err := s.Build(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sim := game.NewSimulator(s.Width, s.Height, seed)
+	for _, e := range entities {
+		sim.AddEntity(e)
+	}
+	return sim
+}
+
+// save returns the snapshot of sim
+func save(t testing.TB, sim *game.Simulator) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := sim.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// runInStep runs a and b side by side for rounds of ticks each and fails as soon as their snapshots differ
+func runInStep(t *testing.T, a *game.Simulator, b *game.Simulator, rounds int, ticks int) {
+	t.Helper()
+	for i := 0; i < rounds; i++ {
+		a.Run(ticks, false)
+		b.Run(ticks, false)
+		if !bytes.Equal(save(t, a), save(t, b)) {
+			t.Fatalf("the worlds differ after %d ticks", a.Ticks())
+		}
+	}
+}
+
+func water(g game.Gameboard) *WaterField {
+	return game.OfType(g, (*WaterField)(nil))[0].(*WaterField)
+}
+
+func soil(g game.Gameboard) *Soil {
+	return game.OfType(g, (*Soil)(nil))[0].(*Soil)
+}
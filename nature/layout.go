@@ -0,0 +1,281 @@
+package nature
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Fill is what a cell of a layout is filled with.
+type Fill int
+
+const (
+	FillAir Fill = iota
+	FillSoil
+	FillRock
+	FillWater
+)
+
+// LayoutCell is a single cell of a layout. Wetness is only used by soil and Volume only by water.
+type LayoutCell struct {
+	Fill    Fill
+	Wetness uint32
+	Volume  int
+}
+
+// Layout is the world a scenario starts with as a grid of cells, which is easier to change one cell at a time than the
+// entities' descriptions. It becomes a scenario with one soil, one rock, the water, the roots and the plant.
+type Layout struct {
+	Width  int
+	Height int
+	// Scale is the number of pixels each cell is drawn with
+	Scale int
+	// cells are the cells of the layout, row by row
+	cells        []LayoutCell
+	plant        image.Point
+	hasPlant     bool
+	rootStart    image.Point
+	hasRootStart bool
+	// roots is the box the roots grow in, an empty box means the box around the soil
+	roots image.Rectangle
+}
+
+// NewLayout returns an empty layout of width x height cells, drawn at scale.
+func NewLayout(width int, height int, scale int) *Layout {
+	return &Layout{
+		Width:  width,
+		Height: height,
+		Scale:  scale,
+		cells:  make([]LayoutCell, width*height),
+	}
+}
+
+// LayoutFromScenario builds the scenario and lays out what it starts with. The scenario must have a single plant and
+// roots, and only the kinds of entities a layout holds. Settings the scenario gives its entities aren't kept, a
+// scenario made from the layout takes them all from the config it is built with.
+func LayoutFromScenario(s *Scenario, config Config) (*Layout, error) {
+	entities, err := s.Build(config)
+	if err != nil {
+		return nil, err
+	}
+	l := NewLayout(s.Width, s.Height, s.Scale)
+	plants, roots := 0, 0
+	for _, e := range entities {
+		switch e := e.(type) {
+		case *Soil:
+			for x := range e.Cells {
+				for y := range e.Cells[x] {
+					if e.Cells[x][y] {
+						l.Set(e.X+x, e.Y+y, LayoutCell{Fill: FillSoil, Wetness: e.wetness[x][y]})
+					}
+				}
+			}
+		case *Rock:
+			for x := range e.Cells {
+				for y := range e.Cells[x] {
+					if e.Cells[x][y] {
+						l.Set(e.X+x, e.Y+y, LayoutCell{Fill: FillRock})
+					}
+				}
+			}
+		case *WaterField:
+			for _, c := range e.initial {
+				volume := c.Volume
+				if l.At(c.X, c.Y).Fill == FillWater {
+					volume += l.At(c.X, c.Y).Volume
+				}
+				l.Set(c.X, c.Y, LayoutCell{Fill: FillWater, Volume: volume})
+			}
+		case *Roots:
+			roots++
+			l.SetRoots(image.Rect(e.X, e.Y, e.X+e.Width(), e.Y+e.Height()))
+			l.SetRootStart(e.X+e.rootRoot.x, e.Y+e.rootRoot.y)
+		case *Plant:
+			plants++
+			l.SetPlant(e.X, e.Y)
+		case *Weather:
+			// every scenario made from a layout has weather
+		default:
+			return nil, fmt.Errorf("a layout can't hold a %T", e)
+		}
+	}
+	if plants != 1 {
+		return nil, fmt.Errorf("a layout needs exactly one plant, the scenario has %d", plants)
+	}
+	if roots != 1 {
+		return nil, fmt.Errorf("a layout needs exactly one roots, the scenario has %d", roots)
+	}
+	return l, nil
+}
+
+func (l *Layout) contains(x int, y int) bool {
+	return x >= 0 && y >= 0 && x < l.Width && y < l.Height
+}
+
+// At returns the cell (x,y), cells off the layout are air.
+func (l *Layout) At(x int, y int) LayoutCell {
+	if !l.contains(x, y) {
+		return LayoutCell{}
+	}
+	return l.cells[y*l.Width+x]
+}
+
+// Set changes the cell (x,y), cells off the layout are left alone.
+func (l *Layout) Set(x int, y int, c LayoutCell) {
+	if l.contains(x, y) {
+		l.cells[y*l.Width+x] = c
+	}
+}
+
+// Plant returns where the plant starts, ok is false if it hasn't been placed.
+func (l *Layout) Plant() (p image.Point, ok bool) {
+	return l.plant, l.hasPlant
+}
+
+// SetPlant moves the plant to (x,y).
+func (l *Layout) SetPlant(x int, y int) {
+	if l.contains(x, y) {
+		l.plant, l.hasPlant = image.Point{x, y}, true
+	}
+}
+
+// RootStart returns the soil cell the roots start from, ok is false if it hasn't been placed.
+func (l *Layout) RootStart() (p image.Point, ok bool) {
+	return l.rootStart, l.hasRootStart
+}
+
+// SetRootStart moves the start of the roots to (x,y).
+func (l *Layout) SetRootStart(x int, y int) {
+	if l.contains(x, y) {
+		l.rootStart, l.hasRootStart = image.Point{x, y}, true
+	}
+}
+
+// Roots returns the box the roots can grow in.
+func (l *Layout) Roots() image.Rectangle {
+	if !l.roots.Empty() {
+		return l.roots
+	}
+	box := image.Rectangle{}
+	for i, c := range l.cells {
+		if c.Fill == FillSoil {
+			box = box.Union(image.Rect(i%l.Width, i/l.Width, i%l.Width+1, i/l.Width+1))
+		}
+	}
+	return box
+}
+
+// SetRoots changes the box the roots can grow in, it is cut down to the layout. An empty box makes the roots cover the
+// box around the soil.
+func (l *Layout) SetRoots(box image.Rectangle) {
+	l.roots = box.Canon().Intersect(image.Rect(0, 0, l.Width, l.Height))
+}
+
+// Scenario describes the layout as a scenario. The soil and rock become one soil and one rock entity each, with gaps
+// wherever the layout has something else.
+func (l *Layout) Scenario() (*Scenario, error) {
+	plant, ok := l.Plant()
+	if !ok {
+		return nil, fmt.Errorf("the layout has no plant")
+	}
+	start, ok := l.RootStart()
+	if !ok {
+		return nil, fmt.Errorf("the layout has no root start")
+	}
+
+	soil := newCellLayer(l.Width, l.Height)
+	rock := newCellLayer(l.Width, l.Height)
+	var water []WaterCell
+	for y := 0; y < l.Height; y++ {
+		for x := 0; x < l.Width; x++ {
+			switch c := l.At(x, y); c.Fill {
+			case FillSoil:
+				// soil cells are written as a single digit
+				if c.Wetness > 9 {
+					return nil, fmt.Errorf("soil at (%d,%d) has wetness %d, scenarios only hold up to 9", x, y, c.Wetness)
+				}
+				soil.set(x, y, byte('0'+c.Wetness))
+			case FillRock:
+				rock.set(x, y, '#')
+			case FillWater:
+				water = append(water, WaterCell{X: x, Y: y, Volume: c.Volume})
+			}
+		}
+	}
+	roots := l.Roots()
+	if !start.In(roots) {
+		return nil, fmt.Errorf("root start (%d,%d) is outside the roots' box %v", start.X, start.Y, roots)
+	}
+
+	specs := []map[string]interface{}{
+		{"kind": "weather"},
+		soil.spec("soil"),
+	}
+	if !rock.bounds().Empty() {
+		specs = append(specs, rock.spec("rock"))
+	}
+	specs = append(specs,
+		map[string]interface{}{"kind": "water", "cells": water},
+		map[string]interface{}{
+			"kind":   "roots",
+			"name":   "roots",
+			"x":      roots.Min.X,
+			"y":      roots.Min.Y,
+			"width":  roots.Dx(),
+			"height": roots.Dy(),
+			"startX": start.X - roots.Min.X,
+			"startY": start.Y - roots.Min.Y,
+		},
+		map[string]interface{}{"kind": "plant", "x": plant.X, "y": plant.Y, "roots": "roots"},
+	)
+
+	s := &Scenario{Width: l.Width, Height: l.Height, Scale: l.Scale}
+	for _, spec := range specs {
+		raw, err := json.Marshal(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.Entities = append(s.Entities, raw)
+	}
+	return s, nil
+}
+
+// cellLayer collects the cells of one kind of ground, as the characters of a soil or rock cells grid
+type cellLayer struct {
+	cells [][]byte
+	box   image.Rectangle
+}
+
+func newCellLayer(width int, height int) *cellLayer {
+	l := &cellLayer{cells: make([][]byte, height)}
+	for y := range l.cells {
+		l.cells[y] = []byte(strings.Repeat(".", width))
+	}
+	return l
+}
+
+func (l *cellLayer) set(x int, y int, c byte) {
+	l.cells[y][x] = c
+	l.box = l.box.Union(image.Rect(x, y, x+1, y+1))
+}
+
+func (l *cellLayer) bounds() image.Rectangle {
+	return l.box
+}
+
+// spec describes the layer as an entity of kind covering the box around its cells
+func (l *cellLayer) spec(kind string) map[string]interface{} {
+	rows := make([]string, 0, l.box.Dy())
+	for y := l.box.Min.Y; y < l.box.Max.Y; y++ {
+		rows = append(rows, string(l.cells[y][l.box.Min.X:l.box.Max.X]))
+	}
+	return map[string]interface{}{
+		"kind":   kind,
+		"x":      l.box.Min.X,
+		"y":      l.box.Min.Y,
+		"width":  l.box.Dx(),
+		"height": l.box.Dy(),
+		"cells":  rows,
+	}
+}
@@ -0,0 +1,138 @@
+package nature
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// Legend says what each color of a map image starts as. Fully transparent pixels are always air.
+type Legend []LegendEntry
+
+// LegendEntry maps the pixels of one color, written as "#rrggbb", to a kind of cell: "air", "soil", "rock", "plant"
+// for where the plant starts, "roots" for the soil cell the roots start from, or "water".
+type LegendEntry struct {
+	Color string `json:"color"`
+	Cell  string `json:"cell"`
+	// Wetness is the starting wetness of soil and root cells
+	Wetness uint32 `json:"wetness,omitempty"`
+	// Volume is the amount of water in water cells, it defaults to 1
+	Volume int `json:"volume,omitempty"`
+}
+
+// DefaultLegend uses the colors the game draws each kind of cell with, so a map looks like the board it makes.
+func DefaultLegend() Legend {
+	return Legend{
+		{Color: "#87cefa", Cell: "air"},
+		{Color: "#c2b280", Cell: "soil"},
+		{Color: "#918560", Cell: "soil", Wetness: 1},
+		{Color: "#615940", Cell: "soil", Wetness: 2},
+		{Color: "#302c20", Cell: "soil", Wetness: 3},
+		{Color: "#808080", Cell: "rock"},
+		{Color: "#00ff00", Cell: "plant"},
+		{Color: "#ffffff", Cell: "roots"},
+		{Color: "#0000ff", Cell: "water", Volume: 1},
+	}
+}
+
+// LoadLegend reads a JSON list of legend entries from r.
+func LoadLegend(r io.Reader) (Legend, error) {
+	var l Legend
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&l); err != nil {
+		return nil, fmt.Errorf("reading legend: %v", err)
+	}
+	if _, err := l.colors(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// colors indexes the legend by color, checking each entry on the way
+func (l Legend) colors() (map[color.NRGBA]LegendEntry, error) {
+	colors := map[color.NRGBA]LegendEntry{}
+	for _, entry := range l {
+		var r, g, b uint8
+		if n, err := fmt.Sscanf(strings.ToLower(entry.Color), "#%02x%02x%02x", &r, &g, &b); n != 3 || err != nil || len(entry.Color) != 7 {
+			return nil, fmt.Errorf("legend: color %q isn't written as #rrggbb", entry.Color)
+		}
+		switch entry.Cell {
+		case "air", "rock", "plant":
+		case "soil", "roots":
+			if entry.Wetness > 9 {
+				return nil, fmt.Errorf("legend: %s wetness %d is more than 9", entry.Color, entry.Wetness)
+			}
+		case "water":
+			if entry.Volume == 0 {
+				entry.Volume = 1
+			}
+			if entry.Volume < 0 {
+				return nil, fmt.Errorf("legend: %s water volume must be at least 1, got %d", entry.Color, entry.Volume)
+			}
+		default:
+			return nil, fmt.Errorf("legend: %s has unknown cell %q", entry.Color, entry.Cell)
+		}
+		c := color.NRGBA{r, g, b, 0xff}
+		if _, ok := colors[c]; ok {
+			return nil, fmt.Errorf("legend: %s is listed more than once", entry.Color)
+		}
+		colors[c] = entry
+	}
+	return colors, nil
+}
+
+// ScenarioFromMap builds a scenario from a map image with one pixel per cell, drawn at scale. The map is read into a
+// layout, so it must have exactly one plant and one root start and the roots can grow anywhere in the box around the
+// soil. The map must be at least MinWidth x MinHeight pixels.
+func ScenarioFromMap(img image.Image, legend Legend, scale int) (*Scenario, error) {
+	colors, err := legend.colors()
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < MinWidth || height < MinHeight {
+		return nil, fmt.Errorf("map is %dx%d pixels, it must be at least %dx%d", width, height, MinWidth, MinHeight)
+	}
+
+	l := NewLayout(width, height, scale)
+	plants, rootStarts := 0, 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if c.A == 0 {
+				continue
+			}
+			entry, ok := colors[c]
+			if !ok {
+				return nil, fmt.Errorf("map pixel (%d,%d) is #%02x%02x%02x, which isn't in the legend", x, y, c.R, c.G, c.B)
+			}
+			switch entry.Cell {
+			case "soil":
+				l.Set(x, y, LayoutCell{Fill: FillSoil, Wetness: entry.Wetness})
+			case "roots":
+				l.Set(x, y, LayoutCell{Fill: FillSoil, Wetness: entry.Wetness})
+				l.SetRootStart(x, y)
+				rootStarts++
+			case "rock":
+				l.Set(x, y, LayoutCell{Fill: FillRock})
+			case "plant":
+				l.SetPlant(x, y)
+				plants++
+			case "water":
+				l.Set(x, y, LayoutCell{Fill: FillWater, Volume: entry.Volume})
+			}
+		}
+	}
+	if plants != 1 {
+		return nil, fmt.Errorf("map must have exactly one plant, it has %d", plants)
+	}
+	if rootStarts != 1 {
+		return nil, fmt.Errorf("map must have exactly one root start, it has %d", rootStarts)
+	}
+	return l.Scenario()
+}
@@ -0,0 +1,130 @@
+package nature
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// decodeMap encodes img as a PNG and decodes it again, the way a map file is read
+func decodeMap(t *testing.T, img image.Image) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestScenarioFromMap(t *testing.T) {
+	var (
+		sky   = color.NRGBA{0x87, 0xce, 0xfa, 0xff}
+		soil  = color.NRGBA{0xc2, 0xb2, 0x80, 0xff}
+		wet   = color.NRGBA{0x61, 0x59, 0x40, 0xff}
+		rock  = color.NRGBA{0x80, 0x80, 0x80, 0xff}
+		plant = color.NRGBA{0x00, 0xff, 0x00, 0xff}
+		roots = color.NRGBA{0xff, 0xff, 0xff, 0xff}
+	)
+	img := image.NewNRGBA(image.Rect(0, 0, MinWidth, MinHeight))
+	for y := 0; y < MinHeight; y++ {
+		for x := 0; x < MinWidth; x++ {
+			switch {
+			case y < 10:
+				img.Set(x, y, sky)
+			case y == 10:
+				img.Set(x, y, wet)
+			default:
+				img.Set(x, y, soil)
+			}
+		}
+	}
+	img.Set(3, 12, rock)
+	img.Set(4, 12, rock)
+	img.Set(12, 9, plant)
+	img.Set(12, 10, roots)
+
+	s, err := ScenarioFromMap(decodeMap(t, img), DefaultLegend(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Width != MinWidth || s.Height != MinHeight || s.Scale != 5 {
+		t.Fatalf("scenario is %dx%d at scale %d, want %dx%d at scale 5", s.Width, s.Height, s.Scale, MinWidth, MinHeight)
+	}
+	entities, err := s.Build(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		gotSoil  *Soil
+		gotRock  *Rock
+		gotRoots *Roots
+		gotPlant *Plant
+	)
+	for _, e := range entities {
+		switch e := e.(type) {
+		case *Soil:
+			gotSoil = e
+		case *Rock:
+			gotRock = e
+		case *Roots:
+			gotRoots = e
+		case *Plant:
+			gotPlant = e
+		}
+	}
+	if gotSoil == nil || gotRock == nil || gotRoots == nil || gotPlant == nil {
+		t.Fatalf("scenario is missing entities: soil %v, rock %v, roots %v, plant %v", gotSoil, gotRock, gotRoots, gotPlant)
+	}
+
+	for y := 0; y < MinHeight; y++ {
+		for x := 0; x < MinWidth; x++ {
+			isRock := (x == 3 || x == 4) && y == 12
+			if got := covers(gotRock.Shape, x, y); got != isRock {
+				t.Errorf("rock at (%d,%d) is %t, want %t", x, y, got, isRock)
+			}
+			isSoil := y >= 10 && !isRock
+			if got := covers(gotSoil.Shape, x, y); got != isSoil {
+				t.Errorf("soil at (%d,%d) is %t, want %t", x, y, got, isSoil)
+			}
+			if !isSoil {
+				continue
+			}
+			// the root start is painted over the wet row in the legend's dry root color
+			wantWetness := uint32(0)
+			if y == 10 && x != 12 {
+				wantWetness = 2
+			}
+			if got := gotSoil.wetness[x-gotSoil.X][y-gotSoil.Y]; got != wantWetness {
+				t.Errorf("soil at (%d,%d) has wetness %d, want %d", x, y, got, wantWetness)
+			}
+		}
+	}
+
+	if gotPlant.X != 12 || gotPlant.Y != 9 {
+		t.Errorf("plant is at (%d,%d), want (12,9)", gotPlant.X, gotPlant.Y)
+	}
+	if x, y := gotRoots.X+gotRoots.rootRoot.x, gotRoots.Y+gotRoots.rootRoot.y; x != 12 || y != 10 {
+		t.Errorf("roots start at (%d,%d), want (12,10)", x, y)
+	}
+	if box := image.Rect(gotRoots.X, gotRoots.Y, gotRoots.X+gotRoots.Width(), gotRoots.Y+gotRoots.Height()); box != image.Rect(0, 10, MinWidth, MinHeight) {
+		t.Errorf("roots cover %v, want the soil's box %v", box, image.Rect(0, 10, MinWidth, MinHeight))
+	}
+}
+
+func TestScenarioFromMapTooSmall(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 20, 12))
+	img.Set(5, 5, color.NRGBA{0x00, 0xff, 0x00, 0xff})
+	img.Set(5, 6, color.NRGBA{0xff, 0xff, 0xff, 0xff})
+	_, err := ScenarioFromMap(decodeMap(t, img), DefaultLegend(), 5)
+	if err == nil || !strings.Contains(err.Error(), "20x12") {
+		t.Errorf("got error %v, want one naming the map's size 20x12", err)
+	}
+}
@@ -1,46 +1,61 @@
 package nature
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
 
 	"github.com/tannerhat/Cactus-Simulator/game"
 )
 
+var plantColor = color.RGBA{0x00, 0xff, 0x00, 0xff}
+
 // Plant is a Shape that takes in water from a root entity and grows bigger from the water.
 type Plant struct {
 	*game.Shape
 	root             *Roots
 	water            uint32
 	speed            int
-	ticks            int
 	waterCostPerCell uint32
+	// winHeight is the height the plant has to grow past to win
+	winHeight int
 }
 
 // NewPlant creates a plant that will start as a 1x1 Shape at x,y. It will SuckWater from root.
-func NewPlant(x int, y int, root *Roots) *Plant {
+func NewPlant(x int, y int, root *Roots, config PlantConfig) *Plant {
 	p := &Plant{
-		Shape:            game.NewShape(x, y, 1, 1, 1, color.RGBA{0x00, 0xff, 0x00, 0xff}),
-		speed:            2,
-		ticks:            0,
+		Shape:            game.NewShape(x, y, 1, 1, 1, plantColor),
+		speed:            config.Speed,
 		root:             root,
-		waterCostPerCell: 800,
+		waterCostPerCell: config.WaterCostPerCell,
+		winHeight:        config.WinHeight,
 	}
 	p.Cells[0][0] = true
 	return p
 }
 
-// Update will take in water from roots once every "speed" ticks. Once it gets enough water to grow, it will expand it's shape.
-func (p *Plant) Update() {
-	p.ticks++
-	if p.ticks%p.speed == 0 {
+// AddToBoard puts the plant's body in the ground layer. Water can still share its cells.
+func (p *Plant) AddToBoard(gameboard game.Gameboard) {
+	p.Shape.AddToBoard(gameboard)
+	p.Occupy(game.GroundLayer, p)
+	p.schedule()
+}
+
+// schedule registers the timer that takes in water from roots once every "speed" ticks
+func (p *Plant) schedule() {
+	p.Gameboard.Every(p, p.speed, 0, func() {
 		p.water += p.root.SuckWater()
-	}
+	})
+}
 
+// Update checks whether the plant has gotten enough water to grow, if it has it will expand it's shape.
+func (p *Plant) Update() {
 	// if the cactus has gotten out of ratio, it gets wider
 	if p.Width() < p.Height()/3 {
 		// growing wider means adding Height cells
 		waterCost := uint32(p.Height()) * p.waterCostPerCell
 		if p.water >= waterCost {
+			p.Vacate(game.GroundLayer, p)
 			p.Cells = append(p.Cells, make([]bool, p.Height()))
 			for y := range p.Cells[p.Width()-1] {
 				p.Cells[p.Width()-1][y] = true
@@ -50,16 +65,23 @@ func (p *Plant) Update() {
 				p.X--
 			}
 			p.water -= waterCost
+			p.Redraw()
+			p.Occupy(game.GroundLayer, p)
+			p.Gameboard.Publish(PlantGrew{Plant: p, Width: p.Width(), Height: p.Height()})
 		}
 	} else {
 		// just get taller (add Width cells)
 		waterCost := uint32(p.Width()) * p.waterCostPerCell
 		if p.water >= waterCost {
+			p.Vacate(game.GroundLayer, p)
 			for x := range p.Cells {
 				p.Cells[x] = append(p.Cells[x], true)
 			}
 			p.Y--
 			p.water -= waterCost
+			p.Redraw()
+			p.Occupy(game.GroundLayer, p)
+			p.Gameboard.Publish(PlantGrew{Plant: p, Width: p.Width(), Height: p.Height()})
 		}
 	}
 
@@ -67,5 +89,69 @@ func (p *Plant) Update() {
 }
 
 func (p *Plant) Win() bool {
-	return p.Height() > 10
+	return p.Height() > p.winHeight
+}
+
+// String summarizes the size of the plant and the water it has stored.
+func (p *Plant) String() string {
+	return fmt.Sprintf("plant at (%d,%d) %dx%d: %d water stored", p.X, p.Y, p.Width(), p.Height(), p.water)
+}
+
+type plantState struct {
+	Shape            *game.Shape
+	Root             game.EntityID
+	Water            uint32
+	Speed            int
+	WaterCostPerCell uint32
+	WinHeight        int
+}
+
+func (p *Plant) Kind() string {
+	return "plant"
+}
+
+func (p *Plant) Snapshot(refs *game.Refs) (interface{}, error) {
+	root, err := refs.Ref(p.root)
+	if err != nil {
+		return nil, err
+	}
+	return plantState{
+		Shape:            p.Shape,
+		Root:             root,
+		Water:            p.water,
+		Speed:            p.speed,
+		WaterCostPerCell: p.waterCostPerCell,
+		WinHeight:        p.winHeight,
+	}, nil
+}
+
+func (p *Plant) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state plantState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Shape == nil {
+		return fmt.Errorf("plant has no shape")
+	}
+	settings := PlantConfig{Speed: state.Speed, WaterCostPerCell: state.WaterCostPerCell, WinHeight: state.WinHeight}
+	if err := settings.validate("plant "); err != nil {
+		return err
+	}
+	e, err := refs.Entity(state.Root)
+	if err != nil {
+		return err
+	}
+	root, ok := e.(*Roots)
+	if !ok {
+		return fmt.Errorf("plant root is a %T, not roots", e)
+	}
+	state.Shape.Gameboard = gameboard
+	p.Shape = state.Shape
+	p.root = root
+	p.water = state.Water
+	p.speed = state.Speed
+	p.waterCostPerCell = state.WaterCostPerCell
+	p.winHeight = state.WinHeight
+	p.schedule()
+	return nil
 }
@@ -0,0 +1,61 @@
+package nature
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+var rockColor = color.RGBA{0x80, 0x80, 0x80, 0xff}
+
+// Rock is ground that water can't soak into and roots can't grow through. Water runs off it like it does off the
+// edges of the board's walls.
+type Rock struct {
+	*game.Solid
+}
+
+// NewRock returns a rock filling the box at (x,y). Clear cells of its Cells matrix before adding it to leave gaps.
+func NewRock(x int, y int, width int, height int) *Rock {
+	r := &Rock{
+		Solid: game.NewSolid(x, y, width, height, 1, rockColor),
+	}
+	for x := range r.Cells {
+		for y := range r.Cells[x] {
+			r.Cells[x][y] = true
+		}
+	}
+	return r
+}
+
+// AddToBoard puts the rock in the ground layer.
+func (r *Rock) AddToBoard(gameboard game.Gameboard) {
+	r.Shape.AddToBoard(gameboard)
+	r.Occupy(game.GroundLayer, r)
+}
+
+type rockState struct {
+	Shape *game.Shape
+}
+
+func (r *Rock) Kind() string {
+	return "rock"
+}
+
+func (r *Rock) Snapshot(refs *game.Refs) (interface{}, error) {
+	return rockState{Shape: r.Shape}, nil
+}
+
+func (r *Rock) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state rockState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Shape == nil {
+		return fmt.Errorf("rock has no shape")
+	}
+	state.Shape.Gameboard = gameboard
+	r.Solid = &game.Solid{Shape: state.Shape}
+	return nil
+}
@@ -1,22 +1,27 @@
 package nature
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
-	"math/rand"
 
-	"github.com/hajimehoshi/ebiten"
-	"github.com/hajimehoshi/ebiten/inpututil"
 	"github.com/tannerhat/Cactus-Simulator/game"
 )
 
-const maxRootWetness uint32 = 3
-
 type Roots struct {
 	*game.Shape
 	rootRoot *rootCell
+	// growRate is the average number of ticks between a root cell's attempts to grow
 	growRate int
-	speed    int
-	ticks    int
+	// speed is the number of ticks between growth attempts
+	speed int
+	// maxWetness is the most water a root cell holds
+	maxWetness uint32
+	// buffer is the picture of the roots, cells are recolored when they get wet or dry out
+	buffer *game.CellBuffer
+	// wetRoots counts the wet root cells at each location, a location can hold more than one root cell and is drawn
+	// wet if any of them are
+	wetRoots [][]int
 }
 
 // rootCell is a single cell of the root. it is not a root in the computer sceince sense
@@ -27,7 +32,7 @@ type rootCell struct {
 	y        int
 }
 
-func NewRoots(x int, y int, width int, height int, startX int, startY int) *Roots {
+func NewRoots(x int, y int, width int, height int, startX int, startY int, config RootsConfig) *Roots {
 	r := &Roots{
 		Shape: game.NewShape(x, y, width, height, 2, color.RGBA{0xff, 0xff, 0xff, 0xff}),
 		rootRoot: &rootCell{
@@ -36,12 +41,13 @@ func NewRoots(x int, y int, width int, height int, startX int, startY int) *Root
 			x:        startX,
 			y:        startY,
 		},
-		growRate: 1500,
-		speed:    500,
-		ticks:    0,
+		growRate:   config.GrowRate,
+		speed:      config.Speed,
+		maxWetness: config.MaxWetness,
 	}
 
 	r.Cells[startX][startY] = true
+	r.redraw()
 
 	return r
 }
@@ -51,10 +57,11 @@ func (rc *rootCell) absorbFromSoil(gameboard game.Gameboard, rootBox *Roots) {
 		child.absorbFromSoil(gameboard, rootBox)
 	}
 
-	if rc.wetness < maxRootWetness {
+	if rc.wetness < rootBox.maxWetness {
 		boardX := rootBox.X + rc.x
 		boardY := rootBox.Y + rc.y
-		entity := gameboard.EntityAt(boardX, boardY)
+		// locations off the board have no soil, so the error doesn't need handling
+		entity, _ := gameboard.EntityOnChecked(game.GroundLayer, boardX, boardY)
 		if soil, ok := entity.(*Soil); ok {
 			// we we only grow into a wet cell
 			waterRemoved, err := soil.RemoveWater(boardX, boardY)
@@ -62,7 +69,8 @@ func (rc *rootCell) absorbFromSoil(gameboard game.Gameboard, rootBox *Roots) {
 				return
 			}
 			if waterRemoved {
-				rc.wetness++
+				rootBox.setWetness(rc, rc.wetness+1)
+				gameboard.Publish(WaterAbsorbed{Absorber: rootBox, X: boardX, Y: boardY})
 				return
 			}
 		}
@@ -77,7 +85,7 @@ func (rc *rootCell) absorbFromSoil(gameboard game.Gameboard, rootBox *Roots) {
 				boardX := rootBox.X + rc.x + dX
 				boardY := rootBox.Y + rc.y + dY
 
-				entity := gameboard.EntityAt(boardX, boardY)
+				entity, _ := gameboard.EntityOnChecked(game.GroundLayer, boardX, boardY)
 				if soil, ok := entity.(*Soil); ok {
 					// we we only grow into a wet cell
 					waterRemoved, err := soil.RemoveWater(boardX, boardY)
@@ -85,7 +93,8 @@ func (rc *rootCell) absorbFromSoil(gameboard game.Gameboard, rootBox *Roots) {
 						return
 					}
 					if waterRemoved {
-						rc.wetness++
+						rootBox.setWetness(rc, rc.wetness+1)
+						gameboard.Publish(WaterAbsorbed{Absorber: rootBox, X: boardX, Y: boardY})
 						return
 					}
 				}
@@ -94,23 +103,23 @@ func (rc *rootCell) absorbFromSoil(gameboard game.Gameboard, rootBox *Roots) {
 	}
 }
 
-func (rc *rootCell) getWaterFromChildren() uint32 {
+func (rc *rootCell) getWaterFromChildren(rootBox *Roots) uint32 {
 	// we can only pass up water already in the root, this must be determined before
 	// getting water from children
 	var waterToPassUp uint32
 	if rc.wetness > 0 {
-		rc.wetness--
+		rootBox.setWetness(rc, rc.wetness-1)
 		waterToPassUp = 1
 	}
 
 	// now get water from children only if we have room, it is possible that
 	// this operation will overload us, that's fine
-	if rc.wetness < maxRootWetness {
+	if rc.wetness < rootBox.maxWetness {
 		waterFromChildren := uint32(0)
 		for _, child := range rc.children {
-			waterFromChildren += child.getWaterFromChildren()
+			waterFromChildren += child.getWaterFromChildren(rootBox)
 		}
-		rc.wetness += waterFromChildren
+		rootBox.setWetness(rc, rc.wetness+waterFromChildren)
 	}
 
 	// pass up original amount (bottlenecked at one)
@@ -129,9 +138,15 @@ func (rc *rootCell) grow(gameboard game.Gameboard, rootBox *Roots) bool {
 	}
 
 	// no children grew. try and get this cell to grow
-	if rand.Intn(rootBox.growRate) == 0 {
-		xDir := -1 + rand.Intn(3)
-		yDir := -1 + rand.Intn(3)
+	rng := gameboard.Rand()
+	// growth is only attempted every speed ticks, so each attempt is more likely to succeed
+	chance := rootBox.growRate / rootBox.speed
+	if chance < 1 {
+		chance = 1
+	}
+	if rng.Intn(chance) == 0 {
+		xDir := -1 + rng.Intn(3)
+		yDir := -1 + rng.Intn(3)
 		if rootBox.AddRoot(rc.x+xDir, rc.y+yDir, gameboard) {
 			rc.children = append(rc.children, &rootCell{
 				children: make([]*rootCell, 0),
@@ -139,6 +154,8 @@ func (rc *rootCell) grow(gameboard game.Gameboard, rootBox *Roots) bool {
 				x:        rc.x + xDir,
 				y:        rc.y + yDir,
 			})
+			rootBox.paint(rc.x+xDir, rc.y+yDir)
+			gameboard.Publish(RootGrown{Roots: rootBox, X: rootBox.X + rc.x + xDir, Y: rootBox.Y + rc.y + yDir})
 		}
 	}
 
@@ -172,7 +189,7 @@ func (r *Roots) AddRoot(x int, y int, gameboard game.Gameboard) bool {
 
 	boardX := r.X + x
 	boardY := r.Y + y
-	entity := gameboard.EntityAt(boardX, boardY)
+	entity, _ := gameboard.EntityOnChecked(game.GroundLayer, boardX, boardY)
 	if soil, ok := entity.(*Soil); ok {
 		// we we only grow into a wet cell
 		wet, err := soil.IsWet(boardX, boardY)
@@ -182,6 +199,7 @@ func (r *Roots) AddRoot(x int, y int, gameboard game.Gameboard) bool {
 
 		if wet {
 			r.Cells[x][y] = true
+			gameboard.SetEntity(game.UndergroundLayer, r, boardX, boardY)
 			return true
 		}
 	}
@@ -189,69 +207,205 @@ func (r *Roots) AddRoot(x int, y int, gameboard game.Gameboard) bool {
 	return false
 }
 
-func (r *Roots) Draw(screen *ebiten.Image, scale int) {
-	cellImage, _ := ebiten.NewImage(scale, scale, ebiten.FilterDefault)
-	defer cellImage.Dispose()
+var (
+	dryRootColor = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	wetRootColor = color.RGBA{0x00, 0x00, 0xff, 0xff}
+)
+
+func (r *Roots) Draw(renderer game.Renderer) {
+	renderer.DrawBuffer(r.buffer)
+}
 
-	// draw dry roots
-	cellImage.Fill(color.RGBA{0xff, 0xff, 0xff, 0xff})
-	r.rootRoot.Draw(r, screen, scale, cellImage, 0)
+// redraw makes a new picture of every root cell
+func (r *Roots) redraw() {
+	r.buffer = game.NewCellBuffer(r.X, r.Y, r.Width(), r.Height())
+	r.wetRoots = make([][]int, r.Width())
+	for x := range r.wetRoots {
+		r.wetRoots[x] = make([]int, r.Height())
+	}
+	r.rootRoot.each(func(rc *rootCell) {
+		if rc.wetness > 0 {
+			r.wetRoots[rc.x][rc.y]++
+		}
+	})
+	r.rootRoot.each(func(rc *rootCell) {
+		r.paint(rc.x, rc.y)
+	})
+}
 
-	// draw all wet roots the same
-	cellImage.Fill(color.RGBA{0x00, 0x00, 0xff, 0xff})
-	for wetness := uint32(1); wetness <= maxRootWetness; wetness++ {
-		r.rootRoot.Draw(r, screen, scale, cellImage, wetness)
+// paint colors the root cell at (x,y) in the roots' picture
+func (r *Roots) paint(x int, y int) {
+	if r.wetRoots[x][y] > 0 {
+		r.buffer.Set(x, y, wetRootColor)
+	} else {
+		r.buffer.Set(x, y, dryRootColor)
 	}
 }
 
-func (rc *rootCell) Draw(box *Roots, screen *ebiten.Image, scale int, image *ebiten.Image, wetness uint32) {
-	rootWetness := rc.wetness
-	if rootWetness > maxRootWetness {
-		rootWetness = maxRootWetness
+// setWetness changes the wetness of rc, repainting its cell if it got wet or dried out
+func (r *Roots) setWetness(rc *rootCell, wetness uint32) {
+	wasWet := rc.wetness > 0
+	rc.wetness = wetness
+	if wasWet == (wetness > 0) {
+		return
 	}
-	if rootWetness == wetness {
-		op := &ebiten.DrawImageOptions{}
-		op.GeoM.Translate(float64((box.X+rc.x)*scale), float64((box.Y+rc.y)*scale))
-		screen.DrawImage(image, op)
+	if wasWet {
+		r.wetRoots[rc.x][rc.y]--
+	} else {
+		r.wetRoots[rc.x][rc.y]++
 	}
+	r.paint(rc.x, rc.y)
+}
 
+// each calls fn for rc and all of its children
+func (rc *rootCell) each(fn func(*rootCell)) {
+	fn(rc)
 	for _, child := range rc.children {
-		child.Draw(box, screen, scale, image, wetness)
+		child.each(fn)
 	}
 }
 
 func (r *Roots) SuckWater() uint32 {
-	return r.rootRoot.getWaterFromChildren()
+	return r.rootRoot.getWaterFromChildren(r)
 }
 
+// Update absorbs water from the soil when the player asks for it, growing happens on its own timer just before
 func (r *Roots) Update() {
-	r.rootRoot.grow(r.Gameboard, r)
-
-	r.ticks++
-	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
+	if r.Gameboard.Triggered(game.ActionAbsorb) {
 		r.rootRoot.absorbFromSoil(r.Gameboard, r)
 	}
 }
 
+// schedule registers the roots' growth timer
+func (r *Roots) schedule() {
+	r.Gameboard.Every(r, r.speed, 0, func() {
+		r.rootRoot.grow(r.Gameboard, r)
+	})
+}
+
 func (r *Roots) AddToBoard(gameBoard game.Gameboard) {
 	r.Shape.AddToBoard(gameBoard)
-	// roots don't take up space on the board, they exist sort of on top
-	// of soil. entities that interact with the cells that roots occupy
-	// should treat the cells as containing soil. they must be in soil though
-	// so we must check that.
+	r.schedule()
+	// roots live in the underground layer, beneath the soil in the ground
+	// layer. entities that interact with the cells that roots occupy
+	// should still treat the cells as containing soil. they must be in soil
+	// though so we must check that.
 
 	for x := range r.Cells {
 		for y := range r.Cells[x] {
 			if r.Cells[x][y] {
 				boardX := r.X + x
 				boardY := r.Y + y
-				entity := r.Gameboard.EntityAt(boardX, boardY)
+				entity, _ := r.Gameboard.EntityOnChecked(game.GroundLayer, boardX, boardY)
 				if _, ok := entity.(*Soil); !ok {
 					panic("creating roots in non soil location, idiot")
 				}
 			}
 		}
 	}
+	r.Occupy(game.UndergroundLayer, r)
 
 	return
 }
+
+// String summarizes the size of the roots and the water they hold.
+func (r *Roots) String() string {
+	cells, water := r.rootRoot.count()
+	return fmt.Sprintf("roots: %d cells holding %d water", cells, water)
+}
+
+// count returns the number of cells and the total wetness of the root starting at rc
+func (rc *rootCell) count() (int, uint32) {
+	cells, water := 1, rc.wetness
+	for _, child := range rc.children {
+		childCells, childWater := child.count()
+		cells += childCells
+		water += childWater
+	}
+	return cells, water
+}
+
+type rootsState struct {
+	Shape      *game.Shape
+	Root       rootCellState
+	GrowRate   int
+	Speed      int
+	MaxWetness uint32
+}
+
+type rootCellState struct {
+	X        int
+	Y        int
+	Wetness  uint32
+	Children []rootCellState
+}
+
+func (rc *rootCell) state() rootCellState {
+	state := rootCellState{
+		X:        rc.x,
+		Y:        rc.y,
+		Wetness:  rc.wetness,
+		Children: make([]rootCellState, len(rc.children)),
+	}
+	for i, child := range rc.children {
+		state.Children[i] = child.state()
+	}
+	return state
+}
+
+func newRootCell(state rootCellState) *rootCell {
+	rc := &rootCell{
+		children: make([]*rootCell, len(state.Children)),
+		wetness:  state.Wetness,
+		x:        state.X,
+		y:        state.Y,
+	}
+	for i, child := range state.Children {
+		rc.children[i] = newRootCell(child)
+	}
+	return rc
+}
+
+func (r *Roots) Kind() string {
+	return "roots"
+}
+
+func (r *Roots) Snapshot(refs *game.Refs) (interface{}, error) {
+	return rootsState{
+		Shape:      r.Shape,
+		Root:       r.rootRoot.state(),
+		GrowRate:   r.growRate,
+		Speed:      r.speed,
+		MaxWetness: r.maxWetness,
+	}, nil
+}
+
+func (r *Roots) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state rootsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Shape == nil {
+		return fmt.Errorf("roots have no shape")
+	}
+	state.Shape.Gameboard = gameboard
+	r.Shape = state.Shape
+	r.rootRoot = newRootCell(state.Root)
+	settings := RootsConfig{GrowRate: state.GrowRate, Speed: state.Speed, MaxWetness: state.MaxWetness}
+	if err := settings.validate("roots "); err != nil {
+		return err
+	}
+	r.growRate = state.GrowRate
+	r.speed = state.Speed
+	r.maxWetness = state.MaxWetness
+	outside := false
+	r.rootRoot.each(func(rc *rootCell) {
+		outside = outside || rc.x < 0 || rc.x >= r.Width() || rc.y < 0 || rc.y >= r.Height()
+	})
+	if outside {
+		return fmt.Errorf("roots have a cell outside their shape")
+	}
+	r.redraw()
+	r.schedule()
+	return nil
+}
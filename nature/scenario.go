@@ -0,0 +1,131 @@
+package nature
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+//go:embed scenarios/default.json
+var defaultScenario []byte
+
+// MinWidth and MinHeight are the smallest board a scenario can have, anything smaller leaves the weather no room for
+// its sun and clouds.
+const (
+	MinWidth  = 24
+	MinHeight = 15
+)
+
+// Scenario describes the world a game starts with: the size of the board, how many pixels each cell is drawn with
+// and the entities on it. Each entity is a JSON object with the kind of entity, an optional name other entities can
+// refer to it by, and the fields of the kind's factory.
+type Scenario struct {
+	Width    int               `json:"width"`
+	Height   int               `json:"height"`
+	Scale    int               `json:"scale"`
+	Entities []json.RawMessage `json:"entities"`
+}
+
+// DefaultScenario returns the world the game has always started with.
+func DefaultScenario() *Scenario {
+	s, err := LoadScenario(bytes.NewReader(defaultScenario))
+	if err != nil {
+		panic(fmt.Sprintf("default scenario: %v", err))
+	}
+	return s
+}
+
+// LoadScenario reads a JSON scenario from r. The entities aren't checked until the scenario is built.
+func LoadScenario(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&s); err != nil {
+		return nil, fmt.Errorf("reading scenario: %v", err)
+	}
+	if err := checkSize(s.Width, s.Height); err != nil {
+		return nil, err
+	}
+	if s.Scale < 1 {
+		return nil, fmt.Errorf("scenario: scale must be at least 1, got %d", s.Scale)
+	}
+	return &s, nil
+}
+
+// Build creates the scenario's entities in the order they are listed, ready to be added to a board of the scenario's
+// size. Settings an entity leaves out are taken from config.
+func (s *Scenario) Build(config Config) ([]game.Entity, error) {
+	if err := checkSize(s.Width, s.Height); err != nil {
+		return nil, err
+	}
+	b := &Builder{
+		Config: config,
+		Width:  s.Width,
+		Height: s.Height,
+		named:  map[string]game.Entity{},
+	}
+
+	entities := make([]game.Entity, 0, len(s.Entities))
+	for i, raw := range s.Entities {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, fmt.Errorf("scenario entity %d: %v", i, err)
+		}
+		var kind, name string
+		if err := unmarshalField(fields, "kind", &kind); err != nil {
+			return nil, fmt.Errorf("scenario entity %d: %v", i, err)
+		}
+		if err := unmarshalField(fields, "name", &name); err != nil {
+			return nil, fmt.Errorf("scenario entity %d: %v", i, err)
+		}
+
+		create, ok := factories[kind]
+		if !ok {
+			return nil, fmt.Errorf("scenario entity %d: unknown kind %q", i, kind)
+		}
+		if _, ok := b.named[name]; ok && name != "" {
+			return nil, fmt.Errorf("scenario entity %d: more than one entity is named %q", i, name)
+		}
+
+		// the factory only sees the kind's own fields
+		spec, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		e, err := create(spec, b)
+		if err != nil {
+			return nil, fmt.Errorf("scenario entity %d (%s): %v", i, kind, err)
+		}
+		if name != "" {
+			b.named[name] = e
+		}
+		b.built = append(b.built, e)
+		entities = append(entities, e)
+	}
+	return entities, nil
+}
+
+// checkSize returns an error if a board of width x height is smaller than MinWidth x MinHeight
+func checkSize(width int, height int) error {
+	if width < MinWidth || height < MinHeight {
+		return fmt.Errorf("scenario: board is %dx%d, it must be at least %dx%d", width, height, MinWidth, MinHeight)
+	}
+	return nil
+}
+
+// unmarshalField decodes fields[key] into v and removes it from fields, v is left alone if there is no such field
+func unmarshalField(fields map[string]json.RawMessage, key string, v interface{}) error {
+	raw, ok := fields[key]
+	if !ok {
+		return nil
+	}
+	delete(fields, key)
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package nature
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+func TestMinimumScenarioRuns(t *testing.T) {
+	sim := newWorld(t, smallScenario(MinWidth, MinHeight), stormyConfig(), 1)
+	rained := false
+	sim.Gameboard().Subscribe(func(e game.Event) {
+		if _, ok := e.(RainStarted); ok {
+			rained = true
+		}
+	})
+	sim.Run(5000, false)
+	if !rained {
+		t.Error("it never rained")
+	}
+}
+
+func TestScenarioTooSmall(t *testing.T) {
+	for _, size := range [][2]int{{MinWidth - 1, MinHeight}, {MinWidth, MinHeight - 1}, {20, 12}, {0, 0}} {
+		want := fmt.Sprintf("board is %dx%d", size[0], size[1])
+		_, err := LoadScenario(strings.NewReader(smallScenario(size[0], size[1])))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("loading a %dx%d scenario: got error %v, want one saying %q", size[0], size[1], err, want)
+		}
+
+		s := &Scenario{Width: size[0], Height: size[1], Scale: 1}
+		if _, err := s.Build(DefaultConfig()); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("building a %dx%d scenario: got error %v, want one saying %q", size[0], size[1], err, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package nature
+
+import (
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// register every nature entity so that snapshots containing them can be loaded
+func init() {
+	game.RegisterKind("cloud", func() game.Snapshotter { return &Cloud{} })
+	game.RegisterKind("plant", func() game.Snapshotter { return &Plant{} })
+	game.RegisterKind("rock", func() game.Snapshotter { return &Rock{} })
+	game.RegisterKind("roots", func() game.Snapshotter { return &Roots{} })
+	game.RegisterKind("soil", func() game.Snapshotter { return &Soil{} })
+	game.RegisterKind("sun", func() game.Snapshotter { return &Sun{} })
+	game.RegisterKind("waterfield", func() game.Snapshotter { return &WaterField{} })
+	game.RegisterKind("weather", func() game.Snapshotter { return &Weather{} })
+}
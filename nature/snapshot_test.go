@@ -0,0 +1,82 @@
+package nature
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// editSnapshot returns saved with edit applied to the state of the first entity of kind. Numbers are kept as written
+// so the random source's state survives the round trip.
+func editSnapshot(t *testing.T, saved []byte, kind string, edit func(state map[string]interface{})) []byte {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(saved))
+	decoder.UseNumber()
+	var snap map[string]interface{}
+	if err := decoder.Decode(&snap); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range snap["Entities"].([]interface{}) {
+		e := e.(map[string]interface{})
+		if e["Kind"] == kind {
+			edit(e["State"].(map[string]interface{}))
+			edited, err := json.Marshal(snap)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return edited
+		}
+	}
+	t.Fatalf("the snapshot has no %s", kind)
+	return nil
+}
+
+// set returns an edit that sets key to value
+func set(key string, value interface{}) func(state map[string]interface{}) {
+	return func(state map[string]interface{}) {
+		state[key] = value
+	}
+}
+
+func TestRestoreRejectsBadSettings(t *testing.T) {
+	sim := newWorld(t, smallScenario(MinWidth, MinHeight), stormyConfig(), 1)
+	for len(game.OfType(sim.Gameboard(), (*Cloud)(nil))) == 0 {
+		sim.Step()
+	}
+	saved := save(t, sim)
+	if _, err := game.LoadSimulator(bytes.NewReader(saved)); err != nil {
+		t.Fatalf("loading the unchanged snapshot: %v", err)
+	}
+
+	for _, c := range []struct {
+		kind string
+		edit func(state map[string]interface{})
+		want string
+	}{
+		{"soil", set("AbsorbRate", 0), "soil absorbRate"},
+		{"soil", set("EvaporateRate", 0), "soil evaporateRate"},
+		{"soil", set("MaxWetness", 0), "soil maxWetness"},
+		{"roots", set("GrowRate", 0), "roots growRate"},
+		{"roots", set("Speed", -1), "roots speed"},
+		{"plant", set("Speed", 0), "plant speed"},
+		{"plant", set("WinHeight", 0), "plant winHeight"},
+		{"weather", set("CloudSpawn", 0), "weather cloudSpawn"},
+		{"weather", set("RainStart", 0), "weather rainStart"},
+		{"weather", set("RainStop", 0), "weather rainStop"},
+		{"weather", set("RainIntensity", 0), "weather rainIntensity"},
+		{"waterfield", set("MaxDensity", 0), "water maxDensity"},
+		{"cloud", set("Rate", 0), "cloud rate"},
+		{"cloud", func(state map[string]interface{}) {
+			shape := state["Shape"].(map[string]interface{})
+			shape["Cells"] = shape["Cells"].([]interface{})[:2]
+		}, "3 cells wide"},
+	} {
+		_, err := game.LoadSimulator(bytes.NewReader(editSnapshot(t, saved, c.kind, c.edit)))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("loading a snapshot with a bad %s: got error %v, want one about %q", c.kind, err, c.want)
+		}
+	}
+}
@@ -1,12 +1,12 @@
 package nature
 
 import (
+	"encoding/json"
 	"fmt"
 	"image/color"
 	"math/rand"
 	"sync"
 
-	"github.com/hajimehoshi/ebiten"
 	"github.com/tannerhat/Cactus-Simulator/game"
 )
 
@@ -15,34 +15,24 @@ type Soil struct {
 	wetness       [][]uint32
 	absorbRate    int
 	evaporateRate int
-	colors        []color.Color
+	maxWetness    uint32
+	colors        []color.RGBA
+	// buffer is the picture of the soil, cells are recolored as their wetness changes
+	buffer *game.CellBuffer
+	// rngs holds the random source of each strip of columns, see Update
+	rngs       []*rand.Rand
+	sequential bool
 }
 
-const maxWetness uint32 = 3
-
-func NewSoil(x int, y int, width int, height int) *Soil {
+func NewSoil(x int, y int, width int, height int, config SoilConfig) *Soil {
 	s := &Soil{
 		Solid:         game.NewSolid(x, y, width, height, 1, color.RGBA{0xc2, 0xb2, 0x80, 0xff}),
-		absorbRate:    3,
-		evaporateRate: 200,
+		absorbRate:    config.AbsorbRate,
+		evaporateRate: config.EvaporateRate,
+		maxWetness:    config.MaxWetness,
 	}
 
-	s.colors = make([]color.Color, maxWetness+1)
-	for wetness := range s.colors {
-		r, g, b, a := color.RGBA{0xc2, 0xb2, 0x80, 0xff}.RGBA()
-		r &= 0xff
-		g &= 0xff
-		b &= 0xff
-		a &= 0xff
-		// max wetness / 2 prevents the soil from being too dark
-		s.colors[wetness] = color.RGBA{
-			uint8(((maxWetness + maxWetness/2) - uint32(wetness)) * r / (maxWetness + maxWetness/2)),
-			uint8(((maxWetness + maxWetness/2) - uint32(wetness)) * g / (maxWetness + maxWetness/2)),
-			uint8(((maxWetness + maxWetness/2) - uint32(wetness)) * b / (maxWetness + maxWetness/2)),
-			uint8(a),
-		}
-
-	}
+	s.initColors()
 
 	for x := range s.Cells {
 		for y := range s.Cells[x] {
@@ -55,84 +45,141 @@ func NewSoil(x int, y int, width int, height int) *Soil {
 	for i := range s.wetness {
 		s.wetness[i] = make([]uint32, height)
 	}
+	s.redraw()
 
 	return s
 }
 
-func (s *Soil) Draw(screen *ebiten.Image, scale int) {
-	cellImage, _ := ebiten.NewImage(scale, scale, ebiten.FilterDefault)
-	defer cellImage.Dispose()
-
-	var wetness uint32
-	for ; wetness <= maxWetness; wetness++ {
-		c := s.getColor(wetness)
-		cellImage.Fill(c)
+func (s *Soil) Draw(r game.Renderer) {
+	r.DrawBuffer(s.buffer)
+}
 
-		for x := range s.Cells {
-			for y := range s.Cells[x] {
-				if s.Cells[x][y] {
-					cellWetness := s.wetness[x][y]
-					if cellWetness > maxWetness {
-						cellWetness = maxWetness
-					}
-					if cellWetness == wetness {
-						// scale color by wetness
-						op := &ebiten.DrawImageOptions{}
-						op.GeoM.Translate(float64((s.X+x)*scale), float64((s.Y+y)*scale))
-						screen.DrawImage(cellImage, op)
-					}
-				}
+// redraw makes a new picture of the whole soil
+func (s *Soil) redraw() {
+	s.buffer = game.NewCellBuffer(s.X, s.Y, s.Width(), s.Height())
+	for x := range s.Cells {
+		for y := range s.Cells[x] {
+			if s.Cells[x][y] {
+				s.buffer.SetRGBA(x, y, s.getColor(s.wetness[x][y]))
 			}
 		}
 	}
+}
 
+// setWetness changes the wetness of the soil cell (x,y) and recolors it, scaling the color by wetness
+func (s *Soil) setWetness(x int, y int, wetness uint32) {
+	s.wetness[x][y] = wetness
+	if s.Cells[x][y] {
+		s.buffer.SetRGBA(x, y, s.getColor(wetness))
+	}
+}
+
+// soak sets every cell of the soil to wetness
+func (s *Soil) soak(wetness uint32) {
+	for x := range s.wetness {
+		for y := range s.wetness[x] {
+			s.setWetness(x, y, wetness)
+		}
+	}
+}
+
+// initColors fills in the color to draw the soil for each wetness level
+func (s *Soil) initColors() {
+	s.colors = soilColors(s.maxWetness)
+}
+
+// soilColors returns the color of soil at each wetness level up to maxWetness, darker the wetter it is
+func soilColors(maxWetness uint32) []color.RGBA {
+	colors := make([]color.RGBA, maxWetness+1)
+	for wetness := range colors {
+		r, g, b, a := color.RGBA{0xc2, 0xb2, 0x80, 0xff}.RGBA()
+		r &= 0xff
+		g &= 0xff
+		b &= 0xff
+		a &= 0xff
+		// max wetness / 2 prevents the soil from being too dark
+		colors[wetness] = color.RGBA{
+			uint8(((maxWetness + maxWetness/2) - uint32(wetness)) * r / (maxWetness + maxWetness/2)),
+			uint8(((maxWetness + maxWetness/2) - uint32(wetness)) * g / (maxWetness + maxWetness/2)),
+			uint8(((maxWetness + maxWetness/2) - uint32(wetness)) * b / (maxWetness + maxWetness/2)),
+			uint8(a),
+		}
+
+	}
+	return colors
 }
 
 // getColor takes the soil coordinates of a cell and returns the color to display the cell as
-func (s *Soil) getColor(wetness uint32) color.Color {
-	if wetness > maxWetness {
-		wetness = maxWetness
+func (s *Soil) getColor(wetness uint32) color.RGBA {
+	if wetness > s.maxWetness {
+		wetness = s.maxWetness
 	}
 	return s.colors[wetness]
 }
 
-func (s *Soil) updateSubGroup(group int, subGroup int, wg *sync.WaitGroup) {
+// soilStripWidth is the number of columns in each strip of soil that Update works on. It must be at least 2 so that
+// strips updated at the same time never touch the same cells.
+const soilStripWidth = 8
 
+var soilDirections = [][]int{
+	[]int{0, 1},
+	[]int{0, -1},
+	[]int{1, 0},
+	[]int{-1, 0},
+	[]int{-1, -1},
+	[]int{-1, 1},
+	[]int{1, 1},
+	[]int{1, -1},
 }
 
-func (s *Soil) Update() {
-	directions := [][]int{
-		[]int{0, 1},
-		[]int{0, -1},
-		[]int{1, 0},
-		[]int{-1, 0},
-		[]int{-1, -1},
-		[]int{-1, 1},
-		[]int{1, 1},
-		[]int{1, -1},
-	}
-
-	for x := 0; x < s.Width(); x++ {
+// SetParallel chooses whether Update spreads the soil's strips across goroutines. Both ways give the same results.
+func (s *Soil) SetParallel(parallel bool) {
+	s.sequential = !parallel
+}
+
+// strips returns the number of strips Update splits the soil into
+func (s *Soil) strips() int {
+	return (s.Width() + soilStripWidth - 1) / soilStripWidth
+}
+
+// updateSubGroup updates strip number subGroup of the soil's strips in group, group 0 is the even strips and group 1
+// the odd ones. Water only moves to neighboring cells, so the strips in a group can all be updated at once.
+func (s *Soil) updateSubGroup(group int, subGroup int, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	strip := 2*subGroup + group
+	rng := s.rngs[strip]
+	start := strip * soilStripWidth
+	end := start + soilStripWidth
+	if end > s.Width() {
+		end = s.Width()
+	}
+
+	for x := start; x < end; x++ {
 		for y := 0; y < s.Height(); y++ {
-			if (s.wetness[x][y] == 1 || (s.wetness[x][y] > 1 && y == 0)) && rand.Intn((y/2+1)*s.evaporateRate) == 0 {
-				s.wetness[x][y]--
+			if !s.Cells[x][y] {
+				// a gap in the soil, nothing to spread
+				continue
+			}
+			if (s.wetness[x][y] == 1 || (s.wetness[x][y] > 1 && y == 0)) && rng.Intn((y/2+1)*s.evaporateRate) == 0 {
+				s.setWetness(x, y, s.wetness[x][y]-1)
 			}
 			if s.wetness[x][y] > 1 {
-				for _, modifier := range directions {
-					if rand.Intn(s.absorbRate) == 0 {
+				for _, modifier := range soilDirections {
+					if rng.Intn(s.absorbRate) == 0 {
 						otherX := x + modifier[0]
 						otherY := y + modifier[1]
 						if otherX >= 0 && otherX < s.Width() &&
-							otherY >= 0 && otherY < s.Height() {
+							otherY >= 0 && otherY < s.Height() && s.Cells[otherX][otherY] {
 							if (s.wetness[x][y]-1 > s.wetness[otherX][otherY]) ||
-								(s.wetness[x][y] > maxWetness) {
-								s.wetness[otherX][otherY]++
-								s.wetness[x][y]--
+								(s.wetness[x][y] > s.maxWetness) {
+								s.setWetness(otherX, otherY, s.wetness[otherX][otherY]+1)
+								s.setWetness(x, y, s.wetness[x][y]-1)
 							}
-						} else if s.wetness[x][y] > maxWetness {
-							// otherX/otherY is off the screen. transfer wetness if we are > max to prevent
-							// soil oversaturation
-							s.wetness[x][y]--
+						} else if s.wetness[x][y] > s.maxWetness {
+							// otherX/otherY is off the screen or a gap in the soil. transfer wetness if we are
+							// > max to prevent soil oversaturation
+							s.setWetness(x, y, s.wetness[x][y]-1)
 						}
 					}
 				}
@@ -141,6 +188,36 @@ func (s *Soil) Update() {
 	}
 }
 
+// Update spreads water between neighboring cells and evaporates it. The soil is split into strips of columns, the
+// even strips are updated together and then the odd ones. Each strip has its own random source, reseeded from the
+// gameboard's every tick, so the result doesn't depend on how many run at once.
+func (s *Soil) Update() {
+	strips := s.strips()
+	if len(s.rngs) != strips {
+		s.rngs = make([]*rand.Rand, strips)
+		for i := range s.rngs {
+			s.rngs[i] = game.NewRand(0)
+		}
+	}
+	rng := s.Gameboard.Rand()
+	for _, stripRng := range s.rngs {
+		stripRng.Seed(rng.Int63())
+	}
+
+	var wg sync.WaitGroup
+	for group := 0; group < 2; group++ {
+		for subGroup := 0; 2*subGroup+group < strips; subGroup++ {
+			wg.Add(1)
+			if s.sequential {
+				s.updateSubGroup(group, subGroup, &wg)
+			} else {
+				go s.updateSubGroup(group, subGroup, &wg)
+			}
+		}
+		wg.Wait()
+	}
+}
+
 // Absorb takes the gameboard coordinates of a soil cell and returns true if that cell successfully absorbs
 func (s *Soil) Absorb(x int, y int) bool {
 	// convert x and y into soil position
@@ -151,8 +228,9 @@ func (s *Soil) Absorb(x int, y int) bool {
 	// of sending its extra water to neighbors. If we don't allow oversaturation,
 	// our absorbtion algorithm doesn't give a way to get non topsoil cells to reach
 	// maxWetness
-	if s.wetness[x][y] < (maxWetness+1) && rand.Intn(s.absorbRate) == 0 {
-		s.wetness[x][y]++
+	if s.wetness[x][y] < (s.maxWetness+1) && s.Gameboard.Rand().Intn(s.absorbRate) == 0 {
+		s.setWetness(x, y, s.wetness[x][y]+1)
+		s.Gameboard.Publish(WaterAbsorbed{Absorber: s, X: x + s.X, Y: y + s.Y})
 		return true
 	}
 
@@ -166,12 +244,57 @@ func (s *Soil) AddToBoard(gameboard game.Gameboard) {
 	for x := range s.Cells {
 		for y := range s.Cells[x] {
 			if s.Cells[x][y] {
-				gameboard.SetEntity(s, s.X+x, s.Y+y)
+				gameboard.SetEntity(game.GroundLayer, s, s.X+x, s.Y+y)
 			}
 		}
 	}
 }
 
+// Dig removes the soil at the board cell (x,y) along with the water in it. Soil that roots have grown into can't be
+// dug. It returns false if the cell is off the board, isn't part of this soil or can't be dug.
+func (s *Soil) Dig(x int, y int) bool {
+	if !s.Gameboard.InBounds(x, y) {
+		return false
+	}
+	if s.Gameboard.EntityOn(game.GroundLayer, x, y) != s || s.Gameboard.EntityOn(game.UndergroundLayer, x, y) != nil {
+		return false
+	}
+	s.setWetness(x-s.X, y-s.Y, 0)
+	s.Cells[x-s.X][y-s.Y] = false
+	s.buffer.SetRGBA(x-s.X, y-s.Y, color.RGBA{})
+	s.Gameboard.SetEntity(game.GroundLayer, nil, x, y)
+	return true
+}
+
+// Fill puts dry soil in the board cell (x,y), which must be a gap in the soil's box. It returns false if the cell is
+// outside the box or anything, water and roots included, is in any layer of it.
+func (s *Soil) Fill(x int, y int) bool {
+	soilX, soilY := x-s.X, y-s.Y
+	if soilX < 0 || soilY < 0 || soilX >= s.Width() || soilY >= s.Height() || s.Cells[soilX][soilY] {
+		return false
+	}
+	if !openCell(s.Gameboard, x, y) {
+		return false
+	}
+	s.Cells[soilX][soilY] = true
+	s.setWetness(soilX, soilY, 0)
+	s.Gameboard.SetEntity(game.GroundLayer, s, x, y)
+	return true
+}
+
+// Dry removes all the water from the soil at the board cell (x,y). It returns false if the cell is off the board,
+// isn't part of this soil or was already dry.
+func (s *Soil) Dry(x int, y int) bool {
+	if !s.Gameboard.InBounds(x, y) {
+		return false
+	}
+	if s.Gameboard.EntityOn(game.GroundLayer, x, y) != s || s.wetness[x-s.X][y-s.Y] == 0 {
+		return false
+	}
+	s.setWetness(x-s.X, y-s.Y, 0)
+	return true
+}
+
 func (s *Soil) IsWet(x int, y int) (bool, error) {
 	// convert x and y into soil position
 	x -= s.X
@@ -194,8 +317,68 @@ func (s *Soil) RemoveWater(x int, y int) (bool, error) {
 	}
 
 	if s.wetness[x][y] > 0 {
-		s.wetness[x][y] = s.wetness[x][y] - 1
+		s.setWetness(x, y, s.wetness[x][y]-1)
 		return true, nil
 	}
 	return false, nil
 }
+
+// String summarizes the soil's wetness.
+func (s *Soil) String() string {
+	var water uint32
+	wetCells := 0
+	for x := range s.wetness {
+		for y := range s.wetness[x] {
+			water += s.wetness[x][y]
+			if s.wetness[x][y] > 0 {
+				wetCells++
+			}
+		}
+	}
+	return fmt.Sprintf("soil at (%d,%d) %dx%d: %d water in %d wet cells", s.X, s.Y, s.Width(), s.Height(), water, wetCells)
+}
+
+type soilState struct {
+	Shape         *game.Shape
+	Wetness       [][]uint32
+	AbsorbRate    int
+	EvaporateRate int
+	MaxWetness    uint32
+}
+
+func (s *Soil) Kind() string {
+	return "soil"
+}
+
+func (s *Soil) Snapshot(refs *game.Refs) (interface{}, error) {
+	return soilState{
+		Shape:         s.Shape,
+		Wetness:       s.wetness,
+		AbsorbRate:    s.absorbRate,
+		EvaporateRate: s.evaporateRate,
+		MaxWetness:    s.maxWetness,
+	}, nil
+}
+
+func (s *Soil) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state soilState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Shape == nil || len(state.Wetness) != state.Shape.Width() || len(state.Wetness[0]) != state.Shape.Height() {
+		return fmt.Errorf("soil wetness doesn't match its shape")
+	}
+	settings := SoilConfig{AbsorbRate: state.AbsorbRate, EvaporateRate: state.EvaporateRate, MaxWetness: state.MaxWetness}
+	if err := settings.validate("soil "); err != nil {
+		return err
+	}
+	state.Shape.Gameboard = gameboard
+	s.Solid = &game.Solid{Shape: state.Shape}
+	s.wetness = state.Wetness
+	s.absorbRate = state.AbsorbRate
+	s.evaporateRate = state.EvaporateRate
+	s.maxWetness = state.MaxWetness
+	s.initColors()
+	s.redraw()
+	return nil
+}
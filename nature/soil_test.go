@@ -0,0 +1,45 @@
+package nature
+
+import (
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+func TestSoilParallelMatchesSequential(t *testing.T) {
+	// wide enough for the soil to be split into several strips
+	scenario := smallScenario(8*soilStripWidth, MinHeight)
+	parallel := newWorld(t, scenario, stormyConfig(), 3)
+	sequential := newWorld(t, scenario, stormyConfig(), 3)
+	soaked := make([]int, 2)
+	for i, sim := range []*game.Simulator{parallel, sequential} {
+		// start the soil wet so it has water to spread from the first tick
+		soil(sim.Gameboard()).soak(2)
+		i, s := i, soil(sim.Gameboard())
+		sim.Gameboard().Subscribe(func(e game.Event) {
+			if e, ok := e.(WaterAbsorbed); ok && e.Absorber == s {
+				soaked[i]++
+			}
+		})
+	}
+	soil(parallel.Gameboard()).SetParallel(true)
+	soil(sequential.Gameboard()).SetParallel(false)
+
+	runInStep(t, parallel, sequential, 4, 2500)
+
+	if soaked[0] == 0 || soaked[0] != soaked[1] {
+		t.Errorf("the soil soaked up %d drops in parallel and %d in sequence, want the same number and some", soaked[0], soaked[1])
+	}
+	// the comparison only means something if the soil still had water moving through it
+	var water uint32
+	moved := false
+	for _, column := range soil(parallel.Gameboard()).wetness {
+		for _, wetness := range column {
+			water += wetness
+			moved = moved || wetness != 2
+		}
+	}
+	if water == 0 || !moved {
+		t.Errorf("the soil holds %d water and moved %t, want water that spread from the soaked start", water, moved)
+	}
+}
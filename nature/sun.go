@@ -1,9 +1,10 @@
 package nature
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
 
-	"github.com/hajimehoshi/ebiten"
 	"github.com/tannerhat/Cactus-Simulator/game"
 )
 
@@ -20,8 +21,38 @@ func NewSun(x int, y int, width int, height int, layer int, color color.Color) *
 	return s
 }
 
-func (s *Sun) Draw(screen *ebiten.Image, scale int) {
+func (s *Sun) Draw(r game.Renderer) {
 	if !s.Hidden {
-		s.Shape.Draw(screen, scale)
+		s.Shape.Draw(r)
 	}
 }
+
+type sunState struct {
+	Shape  *game.Shape
+	Hidden bool
+}
+
+func (s *Sun) Kind() string {
+	return "sun"
+}
+
+func (s *Sun) Snapshot(refs *game.Refs) (interface{}, error) {
+	return sunState{
+		Shape:  s.Shape,
+		Hidden: s.Hidden,
+	}, nil
+}
+
+func (s *Sun) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state sunState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Shape == nil {
+		return fmt.Errorf("sun has no shape")
+	}
+	state.Shape.Gameboard = gameboard
+	s.Shape = state.Shape
+	s.Hidden = state.Hidden
+	return nil
+}
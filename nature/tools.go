@@ -0,0 +1,115 @@
+package nature
+
+import (
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// dryRadius is how far from the cursor the dry out tool reaches
+const dryRadius = 2
+
+// register the tools the player can use on the board with the mouse
+func init() {
+	game.RegisterTool("pour water", pourWater)
+	game.RegisterTool("dig", dig)
+	game.RegisterTool("add soil", addSoil)
+	game.RegisterTool("place rock", placeRock)
+	game.RegisterTool("dry out", dryOut)
+	game.RegisterTool("seed cloud", seedCloud)
+}
+
+// pourWater adds a drop of water to the cell, as long as the water field would take it from the rain
+func pourWater(g game.Gameboard, x int, y int) bool {
+	fields := game.OfType(g, (*WaterField)(nil))
+	if len(fields) == 0 {
+		return false
+	}
+	return fields[0].(*WaterField).AddWater(x, y, 1)
+}
+
+// dig removes the soil from the cell
+func dig(g game.Gameboard, x int, y int) bool {
+	soil, ok := g.EntityOn(game.GroundLayer, x, y).(*Soil)
+	return ok && soil.Dig(x, y)
+}
+
+// addSoil fills a gap in any soil whose box covers the cell, soil can't be added outside of the boxes it started with
+func addSoil(g game.Gameboard, x int, y int) bool {
+	for _, e := range game.OfType(g, (*Soil)(nil)) {
+		if e.(*Soil).Fill(x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// placeRock fills the cell with rock if nothing is in any layer of it. The cell joins a rock next to it, growing that
+// rock's box if it has to, or a rock whose box covers it. Only a cell with no rock around it starts a rock of its own.
+func placeRock(g game.Gameboard, x int, y int) bool {
+	if !openCell(g, x, y) {
+		return false
+	}
+	for _, n := range [][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+		if !g.InBounds(n[0], n[1]) {
+			continue
+		}
+		if rock, ok := g.EntityOn(game.GroundLayer, n[0], n[1]).(*Rock); ok {
+			rock.Grow(x, y)
+			addRock(g, rock, x, y)
+			return true
+		}
+	}
+	for _, e := range game.OfType(g, (*Rock)(nil)) {
+		rock := e.(*Rock)
+		if x >= rock.X && y >= rock.Y && x < rock.X+rock.Width() && y < rock.Y+rock.Height() {
+			addRock(g, rock, x, y)
+			return true
+		}
+	}
+	g.AddEntity(NewRock(x, y, 1, 1))
+	return true
+}
+
+// addRock makes the board cell (x,y), which must be in the rock's box, part of the rock
+func addRock(g game.Gameboard, rock *Rock, x int, y int) {
+	rock.SetCell(x-rock.X, y-rock.Y, true)
+	g.SetEntity(game.GroundLayer, rock, x, y)
+}
+
+// openCell returns true if no layer of the board cell (x,y) holds anything
+func openCell(g game.Gameboard, x int, y int) bool {
+	for _, layer := range game.CellLayers() {
+		if g.EntityOn(layer, x, y) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// dryOut takes the water out of the soil and the water field within dryRadius of the cell
+func dryOut(g game.Gameboard, x int, y int) bool {
+	fields := game.OfType(g, (*WaterField)(nil))
+	dried := false
+	for dX := -dryRadius; dX <= dryRadius; dX++ {
+		for dY := -dryRadius; dY <= dryRadius; dY++ {
+			if dX*dX+dY*dY > dryRadius*dryRadius || !g.InBounds(x+dX, y+dY) {
+				continue
+			}
+			if soil, ok := g.EntityOn(game.GroundLayer, x+dX, y+dY).(*Soil); ok && soil.Dry(x+dX, y+dY) {
+				dried = true
+			}
+			if len(fields) > 0 && fields[0].(*WaterField).Drain(x+dX, y+dY) > 0 {
+				dried = true
+			}
+		}
+	}
+	return dried
+}
+
+// seedCloud starts a cloud in the sky around the cell
+func seedCloud(g game.Gameboard, x int, y int) bool {
+	weather := game.OfType(g, (*Weather)(nil))
+	if len(weather) == 0 {
+		return false
+	}
+	return weather[0].(*Weather).SeedCloud(x, y)
+}
@@ -0,0 +1,217 @@
+package nature
+
+import (
+	"testing"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// toolCase uses a tool on a new minimum-size world: sky in rows 0 to 6 with the plant at (12,6), and soil in rows 7 to
+// 14 with the roots starting at (12,7)
+type toolCase struct {
+	name string
+	// setup changes the world before the tool is used
+	setup func(t *testing.T, sim *game.Simulator)
+	x, y  int
+	want  bool
+	// check looks at the board after the tool was used
+	check func(t *testing.T, g game.Gameboard)
+}
+
+// runToolCases uses tool for each case and checks what it returned
+func runToolCases(t *testing.T, tool string, cases []toolCase) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			sim := newWorld(t, smallScenario(MinWidth, MinHeight), DefaultConfig(), 1)
+			if tc.setup != nil {
+				tc.setup(t, sim)
+			}
+			got, err := sim.UseTool(game.ToolUse{Tool: tool, X: tc.x, Y: tc.y})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Fatalf("%s at (%d,%d) returned %t, want %t", tool, tc.x, tc.y, got, tc.want)
+			}
+			if tc.check != nil {
+				tc.check(t, sim.Gameboard())
+			}
+		})
+	}
+}
+
+// use returns a setup that uses tool on each cell in turn, every use has to work
+func use(tool string, cells ...[2]int) func(t *testing.T, sim *game.Simulator) {
+	return func(t *testing.T, sim *game.Simulator) {
+		for _, c := range cells {
+			if ok, err := sim.UseTool(game.ToolUse{Tool: tool, X: c[0], Y: c[1]}); !ok || err != nil {
+				t.Fatalf("setting up, %s at (%d,%d) returned %t, %v", tool, c[0], c[1], ok, err)
+			}
+		}
+	}
+}
+
+// rocksAt checks that the board has a single rock and that it covers every one of the cells
+func rocksAt(cells ...[2]int) func(t *testing.T, g game.Gameboard) {
+	return func(t *testing.T, g game.Gameboard) {
+		rocks := game.OfType(g, (*Rock)(nil))
+		if len(rocks) != 1 {
+			t.Fatalf("the board has %d rocks, want 1", len(rocks))
+		}
+		for _, c := range cells {
+			if g.EntityOn(game.GroundLayer, c[0], c[1]) != rocks[0] || !covers(rocks[0].(*Rock).Shape, c[0], c[1]) {
+				t.Errorf("(%d,%d) isn't part of the rock", c[0], c[1])
+			}
+		}
+	}
+}
+
+func TestPourWater(t *testing.T) {
+	runToolCases(t, "pour water", []toolCase{
+		{name: "top left corner", x: 0, y: 0, want: true, check: func(t *testing.T, g game.Gameboard) {
+			if v := water(g).Volume(0, 0); v != 1 {
+				t.Errorf("(0,0) holds %d water, want 1", v)
+			}
+		}},
+		{name: "right edge", x: MinWidth - 1, y: 6, want: true},
+		{name: "onto the plant", x: 12, y: 6, want: true},
+		{name: "into soil", x: MinWidth - 1, y: MinHeight - 1, want: false},
+		{name: "onto a full cell", setup: func(t *testing.T, sim *game.Simulator) {
+			for i := 0; i < DefaultConfig().Water.MaxDensity; i++ {
+				use("pour water", [2]int{3, 3})(t, sim)
+			}
+		}, x: 3, y: 3, want: false},
+		{name: "off the left edge", x: -1, y: 0, want: false},
+		{name: "off the bottom", x: 0, y: MinHeight, want: false},
+	})
+}
+
+func TestDig(t *testing.T) {
+	runToolCases(t, "dig", []toolCase{
+		{name: "bottom left corner", x: 0, y: MinHeight - 1, want: true, check: func(t *testing.T, g game.Gameboard) {
+			if e := g.EntityOn(game.GroundLayer, 0, MinHeight-1); e != nil {
+				t.Errorf("the dug cell still holds %T", e)
+			}
+		}},
+		{name: "top right of the soil", x: MinWidth - 1, y: 7, want: true},
+		{name: "over the roots", x: 12, y: 7, want: false},
+		{name: "in the sky", x: 0, y: 0, want: false},
+		{name: "already dug", setup: use("dig", [2]int{0, MinHeight - 1}), x: 0, y: MinHeight - 1, want: false},
+		{name: "off the right edge", x: MinWidth, y: MinHeight - 1, want: false},
+	})
+}
+
+func TestAddSoil(t *testing.T) {
+	runToolCases(t, "add soil", []toolCase{
+		{name: "into a dug corner", setup: use("dig", [2]int{0, MinHeight - 1}), x: 0, y: MinHeight - 1, want: true,
+			check: func(t *testing.T, g game.Gameboard) {
+				if e := g.EntityOn(game.GroundLayer, 0, MinHeight-1); e != soil(g) {
+					t.Errorf("the filled cell holds %T, want the soil", e)
+				}
+			}},
+		{name: "onto soil", x: 0, y: MinHeight - 1, want: false},
+		{name: "above the soil's box", x: 0, y: 0, want: false},
+		{name: "into a gap under a cloud", setup: func(t *testing.T, sim *game.Simulator) {
+			// the cloud is 3x2, the dug cells make room for its bottom row
+			use("dig", [2]int{0, 7}, [2]int{1, 7}, [2]int{2, 7})(t, sim)
+			use("seed cloud", [2]int{1, 7})(t, sim)
+		}, x: 1, y: 7, want: false},
+		{name: "into a gap holding water", setup: func(t *testing.T, sim *game.Simulator) {
+			use("dig", [2]int{0, 7})(t, sim)
+			use("pour water", [2]int{0, 7})(t, sim)
+		}, x: 0, y: 7, want: false},
+		{name: "off the bottom", x: 0, y: MinHeight, want: false},
+	})
+}
+
+func TestPlaceRock(t *testing.T) {
+	runToolCases(t, "place rock", []toolCase{
+		{name: "top left corner", x: 0, y: 0, want: true, check: rocksAt([2]int{0, 0})},
+		{name: "top right corner", x: MinWidth - 1, y: 0, want: true, check: rocksAt([2]int{MinWidth - 1, 0})},
+		{name: "onto soil", x: 0, y: MinHeight - 1, want: false},
+		{name: "onto water", setup: use("pour water", [2]int{5, 0}), x: 5, y: 0, want: false},
+		{name: "onto the plant", x: 12, y: 6, want: false},
+		{name: "over roots", setup: func(t *testing.T, sim *game.Simulator) {
+			g := sim.Gameboard()
+			g.SetEntity(game.UndergroundLayer, game.OfType(g, (*Roots)(nil))[0], 3, 3)
+		}, x: 3, y: 3, want: false},
+		{name: "under a cloud", setup: use("seed cloud", [2]int{3, 3}), x: 3, y: 3, want: false},
+		{name: "next to a rock", setup: use("place rock", [2]int{5, 2}), x: 4, y: 2, want: true,
+			check: rocksAt([2]int{5, 2}, [2]int{4, 2})},
+		{name: "above and left of a rock", setup: use("place rock", [2]int{5, 2}, [2]int{4, 2}), x: 4, y: 1, want: true,
+			check: rocksAt([2]int{5, 2}, [2]int{4, 2}, [2]int{4, 1})},
+		{name: "into a gap in a rock", setup: use("place rock", [2]int{5, 2}, [2]int{5, 3}, [2]int{6, 3}), x: 6, y: 2, want: true,
+			check: rocksAt([2]int{5, 2}, [2]int{5, 3}, [2]int{6, 3}, [2]int{6, 2})},
+		{name: "apart from a rock", setup: use("place rock", [2]int{5, 2}), x: 8, y: 2, want: true,
+			check: func(t *testing.T, g game.Gameboard) {
+				if rocks := game.OfType(g, (*Rock)(nil)); len(rocks) != 2 {
+					t.Errorf("the board has %d rocks, want 2", len(rocks))
+				}
+			}},
+		{name: "off the top", x: 0, y: -1, want: false},
+	})
+}
+
+func TestDryOut(t *testing.T) {
+	runToolCases(t, "dry out", []toolCase{
+		{name: "puddle in the corner", setup: use("pour water", [2]int{0, 0}), x: 0, y: 0, want: true,
+			check: func(t *testing.T, g game.Gameboard) {
+				if v := water(g).Volume(0, 0); v != 0 {
+					t.Errorf("(0,0) still holds %d water", v)
+				}
+			}},
+		{name: "puddle across the edge", setup: use("pour water", [2]int{0, 0}), x: 1, y: 1, want: true},
+		{name: "wet soil in the corner", setup: func(t *testing.T, sim *game.Simulator) {
+			soil(sim.Gameboard()).setWetness(0, MinHeight-1-7, 2)
+		}, x: 0, y: MinHeight - 1, want: true, check: func(t *testing.T, g game.Gameboard) {
+			if w := soil(g).wetness[0][MinHeight-1-7]; w != 0 {
+				t.Errorf("the soil still has wetness %d", w)
+			}
+		}},
+		{name: "dry soil", x: MinWidth - 1, y: MinHeight - 1, want: false},
+		{name: "empty sky", x: 5, y: 2, want: false},
+		{name: "off the edge", setup: use("pour water", [2]int{0, 0}), x: -1, y: -1, want: false},
+	})
+}
+
+func TestSeedCloud(t *testing.T) {
+	runToolCases(t, "seed cloud", []toolCase{
+		{name: "top left corner", x: 0, y: 0, want: true, check: func(t *testing.T, g game.Gameboard) {
+			if clouds := game.OfType(g, (*Cloud)(nil)); len(clouds) != 1 {
+				t.Errorf("the board has %d clouds, want 1", len(clouds))
+			}
+		}},
+		{name: "top right corner", x: MinWidth - 1, y: 0, want: true, check: func(t *testing.T, g game.Gameboard) {
+			c := game.OfType(g, (*Cloud)(nil))[0].(*Cloud)
+			if c.X+c.Width() >= MinWidth {
+				t.Errorf("the cloud reaches x %d, it would drift off at once", c.X+c.Width()-1)
+			}
+		}},
+		{name: "just above the soil", x: 5, y: 6, want: true},
+		{name: "over the soil", x: 12, y: 12, want: false},
+		{name: "off the bottom", x: 0, y: MinHeight, want: false},
+	})
+}
+
+func TestEditsOffTheBoard(t *testing.T) {
+	sim := newWorld(t, smallScenario(MinWidth, MinHeight), DefaultConfig(), 1)
+	g := sim.Gameboard()
+	// without a bounds check (MinWidth,0) is read as (0,1) and a cell left of the soil panics
+	use("pour water", [2]int{0, 1})(t, sim)
+	soil(g).setWetness(MinWidth-1, MinHeight-2-7, 2)
+	if v := water(g).Drain(MinWidth, 0); v != 0 {
+		t.Errorf("draining (%d,0) took %d water, want 0", MinWidth, v)
+	}
+	if v := water(g).Volume(0, 1); v != 1 {
+		t.Errorf("(0,1) holds %d water after draining off the board, want 1", v)
+	}
+	if soil(g).Dig(-1, MinHeight-1) {
+		t.Errorf("digging (-1,%d) worked", MinHeight-1)
+	}
+	if soil(g).Dry(-1, MinHeight-1) {
+		t.Errorf("drying (-1,%d) worked", MinHeight-1)
+	}
+	if w := soil(g).wetness[MinWidth-1][MinHeight-2-7]; w != 2 {
+		t.Errorf("the soil at (%d,%d) has wetness %d after drying off the board, want 2", MinWidth-1, MinHeight-2, w)
+	}
+}
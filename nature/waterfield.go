@@ -0,0 +1,303 @@
+package nature
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+var waterColor = color.RGBA{0x00, 0x00, 0xff, 0xff}
+
+// WaterField holds all of the water on the board as a volume per cell and moves it in a single pass each tick. Cells
+// holding water are covered by the field in the fluid layer.
+type WaterField struct {
+	gameboard game.Gameboard
+	width     int
+	height    int
+	// volume is the amount of water in each cell, row by row
+	volume []int
+	// arrived is the amount of water that flowed into each cell on the tick in arrivedOn, it can't move again until
+	// the next tick
+	arrived   []int
+	arrivedOn []int
+	tick      int
+	// wetCells is the number of cells holding water in each row, rows without any are skipped
+	wetCells []int
+	total    int
+	buffer   *game.CellBuffer
+	// maxDensity is the most water a cell can hold, water can't be forced into a full cell
+	maxDensity int
+	// initial is the water poured in when the field is added to the board
+	initial []WaterCell
+}
+
+// WaterCell is an amount of water in the cell (X,Y).
+type WaterCell struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Volume int `json:"volume"`
+}
+
+// NewWaterField returns an empty water field, it covers the whole board once added.
+func NewWaterField(config WaterConfig) *WaterField {
+	return &WaterField{maxDensity: config.MaxDensity}
+}
+
+func (f *WaterField) AddToBoard(gameboard game.Gameboard) {
+	f.gameboard = gameboard
+	f.width, f.height = gameboard.Size()
+	f.volume = make([]int, f.width*f.height)
+	f.arrived = make([]int, f.width*f.height)
+	f.arrivedOn = make([]int, f.width*f.height)
+	f.wetCells = make([]int, f.height)
+	f.buffer = game.NewCellBuffer(0, 0, f.width, f.height)
+	for _, c := range f.initial {
+		if gameboard.InBounds(c.X, c.Y) {
+			f.change(c.X, c.Y, c.Volume)
+		}
+	}
+	f.initial = nil
+}
+
+func (f *WaterField) Draw(r game.Renderer) {
+	r.DrawBuffer(f.buffer)
+}
+
+func (f *WaterField) Layer() int {
+	return 2
+}
+
+// Volume returns the amount of water in the cell (x,y).
+func (f *WaterField) Volume(x int, y int) int {
+	return f.volume[y*f.width+x]
+}
+
+// AddWater pours amount of water into the cell (x,y). Water can only be added to cells that already hold water or
+// that have nothing but the plant in the ground layer, and not past the field's max density. It returns false if the
+// water couldn't be added.
+func (f *WaterField) AddWater(x int, y int, amount int) bool {
+	if !f.gameboard.InBounds(x, y) {
+		return false
+	}
+	if f.volume[y*f.width+x] == 0 && !openGround(f.gameboard, x, y) {
+		return false
+	}
+	if f.volume[y*f.width+x]+amount > f.maxDensity {
+		return false
+	}
+	f.change(x, y, amount)
+	return true
+}
+
+// Drain removes all the water from the cell (x,y) and returns how much there was, a cell off the board has none.
+func (f *WaterField) Drain(x int, y int) int {
+	if !f.gameboard.InBounds(x, y) {
+		return 0
+	}
+	volume := f.volume[y*f.width+x]
+	if volume > 0 {
+		f.change(x, y, -volume)
+	}
+	return volume
+}
+
+// change adds amount to the volume of (x,y), updating the fluid layer and the field's picture when the cell fills
+// up or dries out
+func (f *WaterField) change(x int, y int, amount int) {
+	i := y*f.width + x
+	before := f.volume[i]
+	f.volume[i] += amount
+	f.total += amount
+	if before == 0 && f.volume[i] > 0 {
+		f.wetCells[y]++
+		f.gameboard.SetEntity(game.FluidLayer, f, x, y)
+		f.buffer.SetRGBA(x, y, waterColor)
+	} else if before > 0 && f.volume[i] == 0 {
+		f.wetCells[y]--
+		f.gameboard.SetEntity(game.FluidLayer, nil, x, y)
+		f.buffer.SetRGBA(x, y, color.RGBA{})
+	}
+}
+
+// Update moves the water that was in each cell at the start of the tick. Rows are updated from the bottom up.
+func (f *WaterField) Update() {
+	if f.total == 0 {
+		return
+	}
+	f.tick++
+	for y := f.height - 1; y >= 0; y-- {
+		if f.wetCells[y] == 0 {
+			continue
+		}
+		for x := 0; x < f.width; x++ {
+			if f.volume[y*f.width+x] > f.arrivedNow(y*f.width+x) {
+				f.updateCell(x, y)
+			}
+		}
+	}
+}
+
+// arrivedNow returns the amount of water that flowed into cell i this tick
+func (f *WaterField) arrivedNow(i int) int {
+	if f.arrivedOn[i] != f.tick {
+		return 0
+	}
+	return f.arrived[i]
+}
+
+// updateCell moves water out of (x,y). It tries to fall first, then to spread sideways, and water that is piled up
+// more than one deep is pushed up and to the sides by the pressure.
+func (f *WaterField) updateCell(x int, y int) {
+	i := y*f.width + x
+	// only water that was here at the start of the tick may move
+	moves := f.volume[i] - f.arrivedNow(i)
+
+	// try to flow down
+	if f.flow(x, y, x, y+1, true, &moves) {
+		return
+	}
+
+	firstDir := -1 + 2*f.gameboard.Rand().Intn(2)
+	// we couldn't go down, try flowing first dir
+	if f.flow(x, y, x+firstDir, y, false, &moves) && f.volume[i] <= 1 {
+		// one drop left, flowing in another direction would leave a gap
+		return
+	}
+	// okay now try the other dir
+	if f.flow(x, y, x-firstDir, y, false, &moves) && f.volume[i] <= 1 {
+		return
+	}
+
+	if f.volume[i] > 1 {
+		// there's more than one drop here, push water up and to the sides
+		if f.flow(x, y, x, y-1, true, &moves) && f.volume[i] <= 1 {
+			return
+		}
+		if f.flow(x, y, x-firstDir, y, true, &moves) && f.volume[i] <= 1 {
+			return
+		}
+		f.flow(x, y, x+firstDir, y, true, &moves)
+	}
+}
+
+// flow tries to move one drop of water from (x,y) to (toX,toY) and returns true if it left (x,y). A drop can join
+// water already at (toX,toY) only if force is set. moves is the number of drops still allowed to leave (x,y) this
+// tick.
+func (f *WaterField) flow(x int, y int, toX int, toY int, force bool, moves *int) bool {
+	if *moves <= 0 || f.volume[y*f.width+x] == 0 {
+		return false
+	}
+
+	toX, toY, boundary := f.gameboard.Locate(toX, toY)
+	switch boundary {
+	case game.Drain:
+		// off the board, the drop is lost
+		f.change(x, y, -1)
+		*moves--
+		return true
+	case game.Wall:
+		return false
+	}
+
+	to := toY*f.width + toX
+	if f.volume[to] > 0 {
+		if !force || f.volume[to] >= f.maxDensity {
+			return false
+		}
+	} else if !openGround(f.gameboard, toX, toY) {
+		soil, ok := f.gameboard.EntityOn(game.GroundLayer, toX, toY).(*Soil)
+		if !ok || !soil.Absorb(toX, toY) {
+			return false
+		}
+		f.change(x, y, -1)
+		*moves--
+		return true
+	}
+
+	f.change(x, y, -1)
+	f.change(toX, toY, 1)
+	f.arrived[to] = f.arrivedNow(to) + 1
+	f.arrivedOn[to] = f.tick
+	*moves--
+	return true
+}
+
+// openGround returns true if water can sit at (x,y), either because the ground layer is empty or because it holds
+// something water runs over, like the plant.
+func openGround(gameBoard game.Gameboard, x int, y int) bool {
+	switch gameBoard.EntityOn(game.GroundLayer, x, y).(type) {
+	case nil, *Plant:
+		return true
+	}
+	return false
+}
+
+// String summarizes the water on the board.
+func (f *WaterField) String() string {
+	cells := 0
+	for _, v := range f.volume {
+		if v > 0 {
+			cells++
+		}
+	}
+	return fmt.Sprintf("water: %d cells holding %d water", cells, f.total)
+}
+
+type waterFieldState struct {
+	Width  int
+	Height int
+	// Volume is the amount of water in each cell, row by row
+	Volume     []int
+	MaxDensity int
+}
+
+func (f *WaterField) Kind() string {
+	return "waterfield"
+}
+
+func (f *WaterField) Snapshot(refs *game.Refs) (interface{}, error) {
+	return waterFieldState{
+		Width:      f.width,
+		Height:     f.height,
+		Volume:     f.volume,
+		MaxDensity: f.maxDensity,
+	}, nil
+}
+
+func (f *WaterField) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state waterFieldState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	width, height := gameboard.Size()
+	if state.Width != width || state.Height != height || len(state.Volume) != width*height {
+		return fmt.Errorf("water field doesn't match the %dx%d board", width, height)
+	}
+	if err := (WaterConfig{MaxDensity: state.MaxDensity}).validate("water "); err != nil {
+		return err
+	}
+
+	f.gameboard = gameboard
+	f.width = width
+	f.height = height
+	f.volume = state.Volume
+	f.maxDensity = state.MaxDensity
+	f.arrived = make([]int, width*height)
+	f.arrivedOn = make([]int, width*height)
+	f.wetCells = make([]int, height)
+	f.buffer = game.NewCellBuffer(0, 0, width, height)
+	f.total = 0
+	for i, v := range f.volume {
+		if v < 0 {
+			return fmt.Errorf("water field has a negative volume at (%d,%d)", i%width, i/width)
+		}
+		if v > 0 {
+			f.buffer.SetRGBA(i%width, i/width, waterColor)
+			f.wetCells[i/width]++
+		}
+		f.total += v
+	}
+	return nil
+}
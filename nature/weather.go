@@ -1,21 +1,25 @@
 package nature
 
 import (
+	"encoding/json"
+	"fmt"
 	"image/color"
-	"math/rand"
 
-	"github.com/hajimehoshi/ebiten"
 	"github.com/tannerhat/Cactus-Simulator/game"
 )
 
 const maxCloudDarkness = 5
 
+// clearSky is the color of the sky when it isn't raining
+var clearSky = color.RGBA{0x87, 0xce, 0xfa, 0xff}
+
 type Weather struct {
-	gameboard     game.Gameboard
-	clouds        []*Cloud
-	cloudSpawn    int
-	skyImage      *ebiten.Image
-	skyColor      color.Color
+	gameboard  game.Gameboard
+	clouds     []*Cloud
+	cloudSpawn int
+	skyColor   color.Color
+	// sky is skyColor darkened by the current rain, it is worked out by recalculateSky
+	sky           color.RGBA
 	sun           *Sun
 	raining       bool
 	rainStart     int
@@ -23,45 +27,25 @@ type Weather struct {
 	rainIntensity int
 }
 
-func NewWeather(cloudSpawn int) *Weather {
+func NewWeather(config WeatherConfig) *Weather {
 	w := &Weather{
 		clouds:        make([]*Cloud, 0),
-		cloudSpawn:    cloudSpawn,
-		skyColor:      color.RGBA{0x87, 0xce, 0xfa, 0xff},
+		cloudSpawn:    config.CloudSpawn,
+		skyColor:      clearSky,
+		sky:           clearSky,
 		raining:       false,
-		rainStart:     20000,
-		rainStop:      3000,
-		rainIntensity: 2,
+		rainStart:     config.RainStart,
+		rainStop:      config.RainStop,
+		rainIntensity: config.RainIntensity,
 	}
 
 	return w
 }
 
-// Draw the entity to the given screen at the given scale
-func (w *Weather) Draw(screen *ebiten.Image, scale int) {
+// Draw the entity through the given renderer
+func (w *Weather) Draw(r game.Renderer) {
 	boardWidth, boardHeight := w.gameboard.Size()
-
-	if w.skyImage == nil {
-		w.skyImage, _ = ebiten.NewImage(boardWidth*scale, boardHeight*scale, ebiten.FilterDefault)
-		w.skyImage.Fill(w.skyColor)
-	}
-
-	if w.sun == nil {
-		w.sun = NewSun(2*boardWidth/3, boardHeight/15+rand.Intn(boardHeight/15), boardWidth/15, boardWidth/15, 0, color.RGBA{0xff, 0xde, 0x00, 0xff})
-
-		for x := range w.sun.Cells {
-			for y := range w.sun.Cells[x] {
-				xEdge := (x == 0 || x == w.sun.Width()-1)
-				yEdge := (y == 0 || y == w.sun.Height()-1)
-				if !xEdge || !yEdge {
-					w.sun.Cells[x][y] = true
-				}
-			}
-		}
-		w.gameboard.AddEntity(w.sun)
-	}
-
-	screen.DrawImage(w.skyImage, nil)
+	r.FillRect(0, 0, boardWidth, boardHeight, w.sky)
 }
 
 func (w *Weather) recalculateSky() {
@@ -81,17 +65,13 @@ func (w *Weather) recalculateSky() {
 	a &= 0xff
 	// max maxCloudDarkness / 2 prevents the sky from being too dark
 
-	newColor := color.RGBA{
+	w.sky = color.RGBA{
 		uint8(((maxCloudDarkness + maxCloudDarkness) - uint32(cloudCount)) * r / (maxCloudDarkness + maxCloudDarkness)),
 		uint8(((maxCloudDarkness + maxCloudDarkness) - uint32(cloudCount)) * g / (maxCloudDarkness + maxCloudDarkness)),
 		uint8(((maxCloudDarkness + maxCloudDarkness) - uint32(cloudCount)) * b / (maxCloudDarkness + maxCloudDarkness)),
 		uint8(a),
 	}
 
-	if w.skyImage != nil {
-		w.skyImage.Fill(newColor)
-	}
-
 	if w.sun != nil {
 		if cloudCount > 1 {
 			w.sun.Hidden = true
@@ -104,21 +84,36 @@ func (w *Weather) recalculateSky() {
 // Update the entity by one tick
 func (w *Weather) Update() {
 	boardWidth, boardHeight := w.gameboard.Size()
+	rng := w.gameboard.Rand()
+
+	if w.sun == nil {
+		// the sun is placed on the first tick rather than on the first draw so its position comes
+		// from the board's random source at a reproducible point in the run
+		w.sun = NewSun(2*boardWidth/3, boardHeight/15+rng.Intn(boardHeight/15), boardWidth/15, boardWidth/15, 0, color.RGBA{0xff, 0xde, 0x00, 0xff})
+
+		for x := range w.sun.Cells {
+			for y := range w.sun.Cells[x] {
+				xEdge := (x == 0 || x == w.sun.Width()-1)
+				yEdge := (y == 0 || y == w.sun.Height()-1)
+				if !xEdge || !yEdge {
+					w.sun.Cells[x][y] = true
+				}
+			}
+		}
+		w.gameboard.AddEntity(w.sun)
+	}
 
 	cloudCount := len(w.clouds)
 	if cloudCount > maxCloudDarkness {
 		cloudCount = maxCloudDarkness
 	}
 
-	if rand.Intn(w.cloudSpawn/(2*cloudCount+1)) == 0 {
+	if rng.Intn(oneIn(w.cloudSpawn/(2*cloudCount+1))) == 0 {
 		cloudWidth := boardWidth / 8
-		c := NewCloud(0, boardHeight/15+rand.Intn(boardHeight/15), cloudWidth, 2*cloudWidth/3, 1)
-		w.clouds = append(w.clouds, c)
-		w.gameboard.AddEntity(c)
-		c.SetStatus(w.raining, w.rainIntensity)
-		w.recalculateSky()
+		w.addCloud(NewCloud(0, boardHeight/15+rng.Intn(boardHeight/15), cloudWidth, 2*cloudWidth/3, 1))
 	}
 
+	moved := false
 	for i := 0; i < len(w.clouds); {
 		c := w.clouds[i]
 
@@ -126,36 +121,101 @@ func (w *Weather) Update() {
 		if !w.raining {
 			effectiveMoveRate /= 4
 		}
-		if rand.Intn(1+effectiveMoveRate) == 0 {
-			c.X++
+		if rng.Intn(1+effectiveMoveRate) == 0 {
+			c.drift()
+			moved = true
 		}
 		if c.X+c.Width() >= boardWidth {
+			c.Vacate(game.AirLayer, c)
 			w.gameboard.RemoveEntity(c)
 			w.clouds = append(w.clouds[:i], w.clouds[i+1:]...)
 			i = 0 // removed an element, start over
 			w.recalculateSky()
+			moved = true
 		} else {
 			i++
 		}
 	}
+	if moved {
+		// a cloud that moved or drifted off may have left cells empty that another cloud still covers
+		for _, c := range w.clouds {
+			c.Occupy(game.AirLayer, c)
+		}
+	}
 
 	if len(w.clouds) > 0 {
 		// there are clouds, determine if we should be raining
-		if w.raining && rand.Intn(w.rainStop) == 0 {
+		if w.raining && rng.Intn(w.rainStop) == 0 {
 			w.toggleRain(false)
-		} else if !w.raining && len(w.clouds) > 0 && rand.Intn(w.rainStart/len(w.clouds)) == 0 {
+		} else if !w.raining && len(w.clouds) > 0 && rng.Intn(oneIn(w.rainStart/len(w.clouds))) == 0 {
 			w.toggleRain(true)
 		}
 	}
 	return
 }
 
+// addCloud puts c in the sky, raining if the rest of the clouds are
+func (w *Weather) addCloud(c *Cloud) {
+	w.clouds = append(w.clouds, c)
+	w.gameboard.AddEntity(c)
+	c.SetStatus(w.raining, w.rainIntensity)
+	w.recalculateSky()
+}
+
+// SeedCloud starts a cloud centered on the board cell (x,y), moved over as far as it takes to fit on the board. It
+// returns false if the cloud would cover any ground.
+func (w *Weather) SeedCloud(x int, y int) bool {
+	boardWidth, boardHeight := w.gameboard.Size()
+	cloudWidth := boardWidth / 8
+	cloudHeight := 2 * cloudWidth / 3
+	// clouds drift off once their right edge reaches the edge of the board, so that edge is left clear
+	maxX, maxY := boardWidth-cloudWidth-1, boardHeight-cloudHeight
+	if cloudWidth < 3 || maxX < 0 || maxY < 0 {
+		return false
+	}
+	x = clamp(x-cloudWidth/2, 0, maxX)
+	y = clamp(y-cloudHeight/2, 0, maxY)
+	for cloudX := x; cloudX < x+cloudWidth; cloudX++ {
+		for cloudY := y; cloudY < y+cloudHeight; cloudY++ {
+			if w.gameboard.EntityOn(game.GroundLayer, cloudX, cloudY) != nil {
+				return false
+			}
+		}
+	}
+	w.addCloud(NewCloud(x, y, cloudWidth, cloudHeight, 1))
+	return true
+}
+
+func clamp(n int, min int, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+// oneIn keeps a chance of one in n from dropping below a certainty when n is worked out from a small setting
+func oneIn(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
 func (w *Weather) toggleRain(enable bool) {
 	w.raining = enable
 	for _, c := range w.clouds {
 		c.SetStatus(enable, w.rainIntensity)
 	}
 	w.recalculateSky()
+
+	if enable {
+		w.gameboard.Publish(RainStarted{})
+	} else {
+		w.gameboard.Publish(RainStopped{})
+	}
 }
 
 // AddToBoard is called by game when an entity is added, the entity marks its initial positions on the game board
@@ -167,3 +227,107 @@ func (w *Weather) AddToBoard(gameboard game.Gameboard) {
 func (w *Weather) Layer() int {
 	return 0
 }
+
+// String summarizes the current weather.
+func (w *Weather) String() string {
+	return fmt.Sprintf("weather: %d clouds, raining: %t", len(w.clouds), w.raining)
+}
+
+type weatherState struct {
+	Clouds        []game.EntityID
+	CloudSpawn    int
+	SkyColor      color.RGBA
+	Sun           game.EntityID
+	Raining       bool
+	RainStart     int
+	RainStop      int
+	RainIntensity int
+}
+
+func (w *Weather) Kind() string {
+	return "weather"
+}
+
+func (w *Weather) Snapshot(refs *game.Refs) (interface{}, error) {
+	state := weatherState{
+		Clouds:        make([]game.EntityID, len(w.clouds)),
+		CloudSpawn:    w.cloudSpawn,
+		SkyColor:      color.RGBAModel.Convert(w.skyColor).(color.RGBA),
+		Raining:       w.raining,
+		RainStart:     w.rainStart,
+		RainStop:      w.rainStop,
+		RainIntensity: w.rainIntensity,
+	}
+
+	for i, c := range w.clouds {
+		ref, err := refs.Ref(c)
+		if err != nil {
+			return nil, err
+		}
+		state.Clouds[i] = ref
+	}
+
+	var sun game.Entity
+	if w.sun != nil {
+		sun = w.sun
+	}
+	ref, err := refs.Ref(sun)
+	if err != nil {
+		return nil, err
+	}
+	state.Sun = ref
+
+	return state, nil
+}
+
+func (w *Weather) Restore(data json.RawMessage, refs *game.Refs, gameboard game.Gameboard) error {
+	var state weatherState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	settings := WeatherConfig{
+		CloudSpawn:    state.CloudSpawn,
+		RainStart:     state.RainStart,
+		RainStop:      state.RainStop,
+		RainIntensity: state.RainIntensity,
+	}
+	if err := settings.validate("weather "); err != nil {
+		return err
+	}
+
+	w.clouds = make([]*Cloud, len(state.Clouds))
+	for i, ref := range state.Clouds {
+		e, err := refs.Entity(ref)
+		if err != nil {
+			return err
+		}
+		c, ok := e.(*Cloud)
+		if !ok {
+			return fmt.Errorf("weather cloud is a %T, not a cloud", e)
+		}
+		w.clouds[i] = c
+	}
+
+	e, err := refs.Entity(state.Sun)
+	if err != nil {
+		return err
+	}
+	if e != nil {
+		sun, ok := e.(*Sun)
+		if !ok {
+			return fmt.Errorf("weather sun is a %T, not a sun", e)
+		}
+		w.sun = sun
+	}
+
+	w.gameboard = gameboard
+	w.cloudSpawn = state.CloudSpawn
+	w.skyColor = state.SkyColor
+	w.raining = state.Raining
+	w.rainStart = state.RainStart
+	w.rainStop = state.RainStop
+	w.rainIntensity = state.RainIntensity
+	// the weather may have been restored mid storm, so the sky has to be recalculated rather than left clear
+	w.recalculateSky()
+	return nil
+}
@@ -0,0 +1,171 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// Bindings maps actions to the keys that trigger them. An action may have several keys but a key only triggers one
+// action.
+type Bindings map[game.Action][]ebiten.Key
+
+// keyLabels overrides how a few keys are shown in the list of controls
+var keyLabels = map[ebiten.Key]string{
+	ebiten.KeyGraveAccent:  "~",
+	ebiten.KeyMinus:        "-",
+	ebiten.KeyEqual:        "=",
+	ebiten.KeyLeftBracket:  "[",
+	ebiten.KeyRightBracket: "]",
+}
+
+// DefaultBindings returns the keys the game has always used.
+func DefaultBindings() Bindings {
+	return Bindings{
+		game.ActionPause:      {ebiten.KeyGraveAccent},
+		game.ActionSpeed1:     {ebiten.Key1},
+		game.ActionSpeed2:     {ebiten.Key2},
+		game.ActionSpeed3:     {ebiten.Key3},
+		game.ActionSpeed4:     {ebiten.Key4},
+		game.ActionSpeedUp:    {ebiten.KeyEqual},
+		game.ActionSpeedDown:  {ebiten.KeyMinus},
+		game.ActionDebug:      {ebiten.KeyD},
+		game.ActionAbsorb:     {ebiten.KeySpace},
+		game.ActionQuickSave:  {ebiten.KeyF5},
+		game.ActionQuickLoad:  {ebiten.KeyF9},
+		game.ActionTimelapse:  {ebiten.KeyF8},
+		game.ActionScreenshot: {ebiten.KeyF12},
+		game.ActionStart:      {ebiten.KeySpace},
+		game.ActionQuit:       {ebiten.KeyEscape},
+		game.ActionEdit:       {ebiten.KeyE},
+		game.ActionNextTool:   {ebiten.KeyTab, ebiten.KeyRightBracket},
+		game.ActionPrevTool:   {ebiten.KeyLeftBracket},
+		game.ActionSaveLevel:  {ebiten.KeyF6},
+	}
+}
+
+// LoadBindings reads bindings from JSON that maps action names to lists of key names, for example
+// {"absorb": ["A", "Space"]}. Key names are the ones used by ebiten.Key. Actions missing from the file keep their
+// default keys.
+func LoadBindings(r io.Reader) (Bindings, error) {
+	var file map[game.Action][]string
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("reading key bindings: %v", err)
+	}
+
+	b := DefaultBindings()
+	for action, names := range file {
+		keys := make([]ebiten.Key, 0, len(names))
+		for _, name := range names {
+			key, ok := keyByName(name)
+			if !ok {
+				return nil, fmt.Errorf("key bindings: unknown key %q for %s", name, action)
+			}
+			keys = append(keys, key)
+		}
+		b[action] = keys
+	}
+
+	if err := b.validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// validate checks that no key is bound to two actions that can be triggered at the same time. Start and quit are
+// only read on the title, edit and win screens so their keys may be reused during play. Tools are used with the mouse
+// so the use tool action can't have keys.
+func (b Bindings) validate() error {
+	if len(b[game.ActionUseTool]) > 0 {
+		return fmt.Errorf("key bindings: %s is used with the mouse, it can't be bound to keys", game.ActionUseTool)
+	}
+	used := map[ebiten.Key]game.Action{}
+	for _, action := range game.Actions() {
+		if action == game.ActionStart || action == game.ActionQuit {
+			continue
+		}
+		for _, key := range b[action] {
+			if other, ok := used[key]; ok {
+				return fmt.Errorf("key bindings: %s is bound to both %s and %s", key, other, action)
+			}
+			used[key] = action
+		}
+	}
+	return nil
+}
+
+// JustTriggered returns true if any of the action's keys were pressed this frame.
+func (b Bindings) JustTriggered(action game.Action) bool {
+	for _, key := range b[action] {
+		if inpututil.IsKeyJustPressed(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// editActions are the actions of the level editor and the tool palette, their keys are shown next to the tools
+var editActions = map[game.Action]bool{
+	game.ActionEdit:      true,
+	game.ActionNextTool:  true,
+	game.ActionPrevTool:  true,
+	game.ActionSaveLevel: true,
+}
+
+// Controls returns a line for every bound action describing which keys trigger it, for the title screen.
+// Start and quit are left out since they are shown on their own screens, and the tool actions are shown with the
+// tools.
+func (b Bindings) Controls() []string {
+	lines := []string{}
+	for _, action := range game.Actions() {
+		if action == game.ActionStart || action == game.ActionQuit || editActions[action] || len(b[action]) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", b.Keys(action), action.Description()))
+	}
+	return lines
+}
+
+// Keys returns the names of the keys bound to action, separated by slashes.
+func (b Bindings) Keys(action game.Action) string {
+	names := make([]string, len(b[action]))
+	for i, key := range b[action] {
+		names[i] = keyLabel(key)
+	}
+	return strings.Join(names, "/")
+}
+
+func keyLabel(key ebiten.Key) string {
+	if label, ok := keyLabels[key]; ok {
+		return label
+	}
+	return strings.ToLower(key.String())
+}
+
+// keyByName finds a key by the name ebiten gives it, ignoring case
+func keyByName(name string) (ebiten.Key, bool) {
+	for key := ebiten.Key(0); key <= ebiten.KeyMax; key++ {
+		if strings.EqualFold(key.String(), name) {
+			return key, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalJSON writes bindings in the format read by LoadBindings.
+func (b Bindings) MarshalJSON() ([]byte, error) {
+	file := map[game.Action][]string{}
+	for action, keys := range b {
+		names := make([]string, len(keys))
+		for i, key := range keys {
+			names[i] = key.String()
+		}
+		file[action] = names
+	}
+	return json.Marshal(file)
+}
@@ -0,0 +1,149 @@
+package window
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// Editor is a level being edited in edit mode. The window turns the mouse into calls to Paint, what the level is and
+// what its tools do is up to the editor.
+type Editor interface {
+	// Tools returns the names of the editor's tools, they are numbered by their place in the list
+	Tools() []string
+	// Paint uses tool on the cell at, as part of a drag with the mouse that started on the cell start
+	Paint(tool int, start image.Point, at image.Point)
+	// Draw draws the level through r
+	Draw(r game.Renderer)
+	// Save writes the level to w
+	Save(w io.Writer) error
+	// Build returns the entities the level starts with, ready to be added to a board of the game's size
+	Build() ([]game.Entity, error)
+}
+
+// editState is the game's edit mode, it is only used on ebiten's goroutine
+type editState struct {
+	editor Editor
+	// path is where the save level key writes the level
+	path string
+	tool int
+	drag drag
+	// message is the result of the last save or attempt to play
+	message string
+}
+
+// SetEditor makes the level editable with editor, the save level key writes it to path. The edit key switches to the
+// editor from the game, and starting the game from the editor replaces the world with the level.
+func (g *Game) SetEditor(editor Editor, path string) {
+	g.edit = &editState{editor: editor, path: path}
+}
+
+// Edit switches the game to edit mode, if it has an editor.
+func (g *Game) Edit() {
+	if g.edit == nil {
+		return
+	}
+	g.mode = ModeEdit
+	l := g.loop
+	l.send(func() { l.running = false })
+}
+
+// updateEdit handles the player's input in edit mode
+func (g *Game) updateEdit() {
+	e := g.edit
+	tools := len(e.editor.Tools())
+	if g.bindings.JustTriggered(game.ActionNextTool) {
+		e.tool = (e.tool + 1) % tools
+	}
+	if g.bindings.JustTriggered(game.ActionPrevTool) {
+		e.tool = (e.tool + tools - 1) % tools
+	}
+	if g.bindings.JustTriggered(game.ActionSaveLevel) {
+		if err := g.saveLevel(); err != nil {
+			e.message = fmt.Sprintf("save failed: %v", err)
+		} else {
+			e.message = fmt.Sprintf("saved level to %s", e.path)
+		}
+		log.Print(e.message)
+	}
+	if g.bindings.JustTriggered(game.ActionStart) {
+		if err := g.play(); err != nil {
+			e.message = fmt.Sprintf("can't play: %v", err)
+			log.Print(e.message)
+		} else {
+			e.message = ""
+			g.mode = ModeGame
+		}
+		return
+	}
+
+	e.drag.update(g.scale, func(start image.Point, at image.Point) {
+		e.editor.Paint(e.tool, start, at)
+	})
+}
+
+// saveLevel writes the level to the editor's path
+func (g *Game) saveLevel() error {
+	f, err := os.Create(g.edit.path)
+	if err != nil {
+		return err
+	}
+	if err := g.edit.editor.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// play replaces the world with a new one built from the level and starts it running. The new world has the same seed
+// as the old one so playing the same level twice plays out the same way.
+func (g *Game) play() error {
+	entities, err := g.edit.editor.Build()
+	if err != nil {
+		return err
+	}
+	l := g.loop
+	err = l.do(func() error {
+		width, height := l.sim.Gameboard().Size()
+		sim := game.NewSimulator(width, height, l.sim.Seed())
+		for _, e := range entities {
+			sim.AddEntity(e)
+		}
+		if err := l.load(sim); err != nil {
+			return err
+		}
+		l.running = true
+		// hand over a frame of the new world before the window looks at whether the game is won
+		l.publish()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	g.shown = l.latest(g.shown)
+	return nil
+}
+
+// drawEditHelp lists the editor's controls and the result of the last save or attempt to play
+func (g *Game) drawEditHelp(screen *ebiten.Image) {
+	e := g.edit
+	msg := fmt.Sprintf(`Tool: %s (%s next, %s previous)
+%s: play, %s: edit again while playing
+%s: save to %s
+%s`,
+		e.editor.Tools()[e.tool],
+		g.bindings.Keys(game.ActionNextTool),
+		g.bindings.Keys(game.ActionPrevTool),
+		g.bindings.Keys(game.ActionStart),
+		g.bindings.Keys(game.ActionEdit),
+		g.bindings.Keys(game.ActionSaveLevel),
+		e.path,
+		e.message)
+	ebitenutil.DebugPrint(screen, msg)
+}
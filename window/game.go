@@ -0,0 +1,350 @@
+package window
+
+import (
+	"fmt"
+	"image/color"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/examples/resources/fonts"
+	"github.com/paulbellamy/ratecounter"
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"golang.org/x/image/font"
+)
+
+var (
+	arcadeFont font.Face
+	fontSize   = 16
+)
+
+// quickSavePath is where the quick save and quick load keys write and read snapshots
+const quickSavePath = "quicksave.json"
+
+type Mode int
+
+const (
+	ModeTitle Mode = iota
+	ModeGame
+	ModeWin
+	ModeEdit
+)
+
+// Game implements ebiten.Game. The simulation runs on its own goroutine, the game passes input to it and draws the
+// frames it hands back.
+type Game struct {
+	drawTime     *ratecounter.AvgRateCounter
+	updateTime   *ratecounter.AvgRateCounter
+	screenHeight int
+	screenWidth  int
+	scale        int
+	mode         Mode
+	bindings     Bindings
+	renderer     *Renderer
+	loop         *simLoop
+	// shown is the frame being drawn, the loop doesn't touch it until it is handed back for a newer one
+	shown           *frame
+	timelapseEvery  int
+	timelapseShrink int
+	// edit is the level editor, it is nil if the game wasn't given one
+	edit *editState
+	// tool is the selected tool of the palette, toolDrag follows the mouse while it is being used
+	tool     int
+	toolDrag drag
+}
+
+func init() {
+	tt, err := truetype.Parse(fonts.ArcadeN_ttf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	const dpi = 72
+	arcadeFont = truetype.NewFace(tt, &truetype.Options{
+		Size:    float64(fontSize),
+		DPI:     dpi,
+		Hinting: font.HintingFull,
+	})
+}
+
+// Update reads the player's input and passes it on to the simulation, which applies it between ticks.
+func (g *Game) Update(screen *ebiten.Image) error {
+	updateStart := time.Now()
+	l := g.loop
+
+	if g.mode == ModeGame {
+		for _, action := range game.Actions() {
+			if action.Recorded() && g.bindings.JustTriggered(action) {
+				a := action
+				l.send(func() { l.act(a) })
+			}
+		}
+		if g.bindings.JustTriggered(game.ActionQuickSave) {
+			l.send(func() {
+				if err := l.quickSave(); err != nil {
+					log.Printf("quick save failed: %v", err)
+				}
+			})
+		}
+		if g.bindings.JustTriggered(game.ActionQuickLoad) {
+			l.send(func() {
+				if l.player != nil {
+					return
+				}
+				if err := l.quickLoad(); err != nil {
+					log.Printf("quick load failed: %v", err)
+				}
+			})
+		}
+		if g.bindings.JustTriggered(game.ActionScreenshot) {
+			if err := g.screenshot(); err != nil {
+				log.Printf("screenshot failed: %v", err)
+			}
+		}
+		g.updateTools()
+		if g.bindings.JustTriggered(game.ActionTimelapse) {
+			every, shrink := g.timelapseEvery, g.timelapseShrink
+			l.send(func() {
+				if err := l.toggleTimelapse(every, shrink); err != nil {
+					log.Printf("timelapse failed: %v", err)
+				}
+			})
+		}
+		if g.bindings.JustTriggered(game.ActionEdit) {
+			g.Edit()
+		} else if g.shown != nil && g.shown.won {
+			g.mode = ModeWin
+		}
+	} else if g.mode == ModeEdit {
+		g.updateEdit()
+	} else if g.mode == ModeTitle {
+		if g.bindings.JustTriggered(game.ActionStart) {
+			g.mode = ModeGame
+			l.send(func() { l.running = true })
+		}
+	} else if g.mode == ModeWin {
+		if g.bindings.JustTriggered(game.ActionQuit) {
+			return fmt.Errorf("game dones")
+		}
+		if g.bindings.JustTriggered(game.ActionEdit) {
+			g.Edit()
+		} else if g.shown != nil && !g.shown.won {
+			// a snapshot from before the win was loaded
+			g.mode = ModeGame
+		}
+	}
+	g.updateTime.Incr(int64(time.Since(updateStart)))
+	return nil
+}
+
+// Draw writes the screen image to the given ebiten.Image, drawing the newest frame the simulation has handed over.
+func (g *Game) Draw(screen *ebiten.Image) {
+	drawsStart := time.Now()
+	g.shown = g.loop.latest(g.shown)
+	g.renderer.SetScreen(screen)
+	g.drawFrame(g.renderer)
+
+	if g.mode == ModeGame || g.mode == ModeWin {
+		g.drawTime.Incr(int64(time.Since(drawsStart)))
+
+		if g.shown != nil && g.shown.debug {
+			msg := fmt.Sprintf(`FPS: %0.2f
+Draw Time: %0.2f ms
+Update Time: %0.2f ms
+Step Time: %0.2f ms
+Speed: %d
+Game time: %0.2f hours
+Seed: %d`,
+				ebiten.CurrentFPS(),
+				g.drawTime.Rate()/float64(time.Millisecond),
+				g.updateTime.Rate()/float64(time.Millisecond),
+				g.shown.stepTime/float64(time.Millisecond),
+				g.shown.speed,
+				g.shown.hours,
+				g.shown.seed)
+			ebitenutil.DebugPrint(screen, msg)
+		}
+		if g.mode == ModeGame {
+			g.drawTools(screen)
+		}
+	} else if g.mode == ModeEdit {
+		g.drawEditHelp(screen)
+	}
+}
+
+// drawFrame draws everything the current mode shows through r, other than the debug info
+func (g *Game) drawFrame(r game.Renderer) {
+	if g.mode == ModeGame || g.mode == ModeWin {
+		if g.shown != nil {
+			g.shown.board.Draw(r)
+		}
+
+		if g.mode == ModeWin {
+			texts := []string{"", "", "", "YOU GREW THE PERFECT:", "", "CACTUS", "", fmt.Sprintf("Press %s to Leave.", g.bindings.Keys(game.ActionQuit))}
+			for i, l := range texts {
+				x := (g.screenWidth - len(l)*fontSize) / 2
+				r.DrawText(l, x, (i+4)*fontSize, color.White)
+			}
+		}
+	} else if g.mode == ModeEdit {
+		g.edit.editor.Draw(r)
+	} else if g.mode == ModeTitle {
+		texts := []string{"Welcome To Cactus Simulator", "", "Controls:"}
+		texts = append(texts, g.bindings.Controls()...)
+		texts = append(texts, "", "", fmt.Sprintf("Press %s to start", g.bindings.Keys(game.ActionStart)))
+		for i, l := range texts {
+			x := (g.screenWidth - len(l)*fontSize) / 2
+			r.DrawText(l, x, (i+4)*fontSize, color.White)
+		}
+	}
+}
+
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return g.screenWidth, g.screenHeight
+}
+
+// AddEntity adds the given entity to the game's board.
+func (g *Game) AddEntity(entity game.Entity) {
+	l := g.loop
+	l.do(func() error {
+		l.sim.AddEntity(entity)
+		return nil
+	})
+}
+
+// Save writes a snapshot of the game's simulation to w.
+func (g *Game) Save(w io.Writer) error {
+	l := g.loop
+	return l.do(func() error {
+		return l.sim.Save(w)
+	})
+}
+
+// Load replaces the game's simulation with the snapshot read from r, stopping any replay being played. The snapshot's
+// board must be the same size as the game's board.
+func (g *Game) Load(r io.Reader) error {
+	sim, err := game.LoadSimulator(r)
+	if err != nil {
+		return err
+	}
+	l := g.loop
+	return l.do(func() error {
+		return l.load(sim)
+	})
+}
+
+// Record starts recording the session from the current state of the world, replacing any recording in progress.
+func (g *Game) Record() error {
+	l := g.loop
+	return l.do(l.record)
+}
+
+// Recording returns the session recorded since Record was called, or nil if it never was.
+func (g *Game) Recording() *game.Replay {
+	var recording *game.Replay
+	l := g.loop
+	l.do(func() error {
+		recording = l.recording
+		return nil
+	})
+	return recording
+}
+
+// Play replaces the game's world with the start of the replay and plays back its actions instead of reading the
+// keyboard until the replay runs out.
+func (g *Game) Play(replay *game.Replay) error {
+	player, err := replay.Play()
+	if err != nil {
+		return err
+	}
+	l := g.loop
+	return l.do(func() error {
+		if err := l.replace(player.Simulator(), "replay"); err != nil {
+			return err
+		}
+		l.player = player
+		return nil
+	})
+}
+
+// Screenshot writes the frame the game is showing to w as a PNG, at the size it is drawn in the window.
+func (g *Game) Screenshot(w io.Writer) error {
+	width, height := g.screenWidth/g.scale, g.screenHeight/g.scale
+	r := game.NewImageRenderer(width, height, g.scale)
+	r.SetFace(arcadeFont)
+	// the window starts each frame from black
+	r.FillRect(0, 0, width, height, color.Black)
+	g.drawFrame(r)
+	return png.Encode(w, r.Image())
+}
+
+// ExportBoard writes the board to w as a PNG with one pixel per cell, whatever scale the game is shown at.
+func (g *Game) ExportBoard(w io.Writer) error {
+	l := g.loop
+	return l.do(func() error {
+		return png.Encode(w, game.BoardImage(l.sim.Gameboard()))
+	})
+}
+
+// screenshot saves the current frame to a PNG named after the time it was taken
+func (g *Game) screenshot() error {
+	path := fmt.Sprintf("screenshot-%s.png", time.Now().Format("20060102-150405.000"))
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := g.Screenshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	log.Printf("saved screenshot to %s", path)
+	return f.Close()
+}
+
+// SetTimelapse changes how often the timelapse key captures a frame and how many cells across each of its pixels
+// averages. It takes effect the next time a timelapse is started.
+func (g *Game) SetTimelapse(every int, shrink int) {
+	g.timelapseEvery = every
+	g.timelapseShrink = shrink
+}
+
+// StopTimelapse finishes the timelapse being captured, if there is one.
+func (g *Game) StopTimelapse() error {
+	l := g.loop
+	return l.do(l.stopTimelapse)
+}
+
+// SetBindings changes the keys that trigger each action.
+func (g *Game) SetBindings(bindings Bindings) {
+	g.bindings = bindings
+}
+
+// Close stops the simulation. The game's methods still work on the world as it was left, but it no longer changes.
+func (g *Game) Close() {
+	g.loop.close()
+}
+
+// NewGame creates a game with the given screen width and height. Scale indicates how many pixels per cell in the gameboard.
+// The simulation's random source is seeded with seed, it starts running on its own goroutine.
+func NewGame(width int, height int, scale int, seed int64) *Game {
+	g := Game{
+		drawTime:     ratecounter.NewAvgRateCounter(time.Second),
+		updateTime:   ratecounter.NewAvgRateCounter(time.Second),
+		screenWidth:  width,
+		screenHeight: height,
+		scale:        scale,
+		mode:         ModeTitle,
+		bindings:     DefaultBindings(),
+		renderer:     NewRenderer(scale, arcadeFont),
+		// a frame every game minute by default
+		timelapseEvery:  60 * 60,
+		timelapseShrink: 1,
+	}
+	g.loop = newSimLoop(game.NewSimulator(g.screenWidth/g.scale, g.screenHeight/g.scale, seed), scale)
+
+	return &g
+}
@@ -0,0 +1,381 @@
+package window
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/paulbellamy/ratecounter"
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// frameRate is the number of times a second the simulation loop steps the simulation speed ticks and records a frame,
+// it matches ebiten's update rate so a speed means the same as it did when the window stepped the simulation itself
+const frameRate = 60
+
+// frame is what the simulation loop hands to the window to draw, nothing in it changes once it has been handed over
+type frame struct {
+	board *game.DrawList
+	ticks int
+	hours float64
+	seed  int64
+	speed int
+	won   bool
+	debug bool
+	// stepTime is the average time the loop took to step the simulation each frame over the last second
+	stepTime float64
+	// replaying is true while a replay's actions are being played back
+	replaying bool
+}
+
+// simLoop owns the simulator and everything that changes with it, and runs it on its own goroutine so a slow tick
+// never holds up the window. The window talks to it through commands, which run on the loop's goroutine between
+// ticks, and the loop hands back frames through a set of three that are passed around between them.
+type simLoop struct {
+	sim   *game.Simulator
+	speed int
+	// debug is toggled by an action, so it is kept here where replayed actions can toggle it too
+	debug    bool
+	stepTime *ratecounter.AvgRateCounter
+	// running is false until the title screen is left
+	running bool
+	// recording collects the player's actions when the session is being recorded
+	recording *game.Replay
+	// player feeds actions from a replay instead of the keyboard when a replay is being watched
+	player *game.Player
+	// timelapse captures frames while the timelapse key has it turned on
+	timelapse *game.Timelapse
+
+	scale    int
+	commands chan func()
+	// ready holds the newest frame the window hasn't taken yet, free holds the frames neither side is using
+	ready chan *frame
+	free  chan *frame
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// newSimLoop starts a loop running sim, drawing its frames at scale.
+func newSimLoop(sim *game.Simulator, scale int) *simLoop {
+	l := &simLoop{
+		sim:      sim,
+		speed:    1,
+		stepTime: ratecounter.NewAvgRateCounter(time.Second),
+		scale:    scale,
+		commands: make(chan func(), 64),
+		ready:    make(chan *frame, 1),
+		free:     make(chan *frame, 3),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for i := 0; i < cap(l.free); i++ {
+		l.free <- &frame{board: game.NewDrawList(scale)}
+	}
+	go l.run()
+	return l
+}
+
+func (l *simLoop) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(time.Second / frameRate)
+	defer ticker.Stop()
+
+	l.publish()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case command := <-l.commands:
+			command()
+		case <-ticker.C:
+			// a won game stays as it is
+			if l.running && !l.sim.Won() {
+				stepStart := time.Now()
+				l.advance()
+				l.stepTime.Incr(int64(time.Since(stepStart)))
+			}
+			l.publish()
+		}
+	}
+}
+
+// advance steps the simulation speed times
+func (l *simLoop) advance() {
+	if l.speed == 0 {
+		// the replay's actions for the next tick are applied even while paused, otherwise a recorded unpause would
+		// never be reached
+		if l.player != nil {
+			l.playReplay()
+		}
+		return
+	}
+	for i := 0; i < l.speed; i++ {
+		if l.player != nil {
+			l.playReplay()
+		}
+		if l.sim.Step() {
+			// the game stops once the plant wins
+			l.speed = 0
+		}
+		if l.timelapse != nil {
+			if err := l.timelapse.Capture(l.sim); err != nil {
+				log.Printf("timelapse failed: %v", err)
+				l.stopTimelapse()
+			}
+		}
+	}
+}
+
+// publish records the board into a free frame and hands it to the window, replacing a frame the window hasn't taken
+// yet. If the window is holding on to every other frame the board isn't recorded this time.
+func (l *simLoop) publish() {
+	var f *frame
+	select {
+	case f = <-l.free:
+	default:
+		return
+	}
+
+	f.board.Reset()
+	game.DrawBoard(f.board, l.sim.Gameboard())
+	f.ticks = l.sim.Ticks()
+	f.hours = l.sim.Hours()
+	f.seed = l.sim.Seed()
+	f.speed = l.speed
+	f.won = l.sim.Won()
+	f.debug = l.debug
+	f.stepTime = l.stepTime.Rate()
+	f.replaying = l.player != nil
+
+	select {
+	case old := <-l.ready:
+		l.free <- old
+	default:
+	}
+	// only the loop sends to ready and it was just emptied, so this never blocks
+	l.ready <- f
+}
+
+// latest returns the newest frame from the loop, or shown if there isn't a newer one. shown is given back to the loop
+// once it is replaced.
+func (l *simLoop) latest(shown *frame) *frame {
+	select {
+	case f := <-l.ready:
+		if shown != nil {
+			l.free <- shown
+		}
+		return f
+	default:
+		return shown
+	}
+}
+
+// do runs fn on the loop's goroutine between ticks and waits for it to finish. Once the loop has stopped fn runs on
+// the caller's goroutine instead.
+func (l *simLoop) do(fn func() error) error {
+	result := make(chan error, 1)
+	select {
+	case l.commands <- func() { result <- fn() }:
+	case <-l.done:
+		return fn()
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-l.done:
+		select {
+		case err := <-result:
+			return err
+		default:
+			// the loop stopped before getting to the command, it won't run it now
+			return fn()
+		}
+	}
+}
+
+// send runs fn on the loop's goroutine between ticks without waiting for it.
+func (l *simLoop) send(fn func()) {
+	select {
+	case l.commands <- fn:
+	case <-l.done:
+	}
+}
+
+// close stops the loop and waits for it to finish.
+func (l *simLoop) close() {
+	select {
+	case <-l.stop:
+	default:
+		close(l.stop)
+	}
+	<-l.done
+}
+
+// act records action if the session is being recorded and then applies it. Actions always take effect on the next
+// tick so that is the tick they are recorded for. Actions from the keyboard are ignored while a replay is playing.
+func (l *simLoop) act(action game.Action) {
+	if l.player != nil {
+		return
+	}
+	if l.recording != nil {
+		l.recording.Record(l.sim.Ticks()+1, action)
+	}
+	l.apply(action)
+}
+
+// apply carries out action, simulated actions are passed to the simulator for its next tick.
+func (l *simLoop) apply(action game.Action) {
+	if speed, ok := game.ApplySpeed(action, l.speed); ok {
+		l.speed = speed
+		return
+	}
+
+	switch action {
+	case game.ActionDebug:
+		l.debug = !l.debug
+	default:
+		if action.Simulated() {
+			l.sim.Trigger(action)
+		}
+	}
+}
+
+// useTools uses tools on the board between ticks, recording the uses that change it if the session is being
+// recorded. Like actions they are recorded for the next tick, and ignored while a replay is playing.
+func (l *simLoop) useTools(uses []game.ToolUse) {
+	if l.player != nil {
+		return
+	}
+	for _, use := range uses {
+		used, err := l.sim.UseTool(use)
+		if err != nil {
+			log.Printf("using tool: %v", err)
+			return
+		}
+		if used && l.recording != nil {
+			l.recording.RecordTool(l.sim.Ticks()+1, use)
+		}
+	}
+}
+
+// playReplay applies the replay's actions and tool uses for the next tick. Once the replay runs out the keyboard
+// takes over.
+func (l *simLoop) playReplay() {
+	for _, e := range l.player.Next() {
+		if e.Tool != nil {
+			if err := l.player.UseTool(*e.Tool); err != nil {
+				log.Printf("replay stopped: %v", err)
+				l.player = nil
+				return
+			}
+		} else {
+			l.apply(e.Action)
+		}
+	}
+	if l.player.Done() {
+		l.player = nil
+	}
+}
+
+// replace switches the loop to sim, kind names where it came from for the error if its board isn't the same size.
+// Anything subscribed to the old board's events is moved over so that loading a snapshot or replay doesn't silently
+// disconnect it.
+func (l *simLoop) replace(sim *game.Simulator, kind string) error {
+	oldWidth, oldHeight := l.sim.Gameboard().Size()
+	width, height := sim.Gameboard().Size()
+	if width != oldWidth || height != oldHeight {
+		return fmt.Errorf("%s board is %dx%d, game board is %dx%d", kind, width, height, oldWidth, oldHeight)
+	}
+	l.sim.HandOver(sim)
+	l.sim = sim
+	return nil
+}
+
+// load switches the loop to sim, restarting the recording from it if there is one. A replay being watched is stopped,
+// its actions don't apply to the loaded world.
+func (l *simLoop) load(sim *game.Simulator) error {
+	if err := l.replace(sim, "snapshot"); err != nil {
+		return err
+	}
+	l.player = nil
+	if l.recording != nil {
+		// the actions recorded so far don't apply to the loaded world, start over from it
+		return l.record()
+	}
+	return nil
+}
+
+// record starts recording from the current state of the world, replacing any recording in progress
+func (l *simLoop) record() error {
+	r, err := game.NewReplay(l.sim)
+	if err != nil {
+		return err
+	}
+	l.recording = r
+	return nil
+}
+
+func (l *simLoop) quickSave() error {
+	f, err := os.Create(quickSavePath)
+	if err != nil {
+		return err
+	}
+	if err := l.sim.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (l *simLoop) quickLoad() error {
+	f, err := os.Open(quickSavePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sim, err := game.LoadSimulator(f)
+	if err != nil {
+		return err
+	}
+	return l.load(sim)
+}
+
+// startTimelapse starts capturing a timelapse to path
+func (l *simLoop) startTimelapse(path string, every int, shrink int) error {
+	out, err := game.CreateTimelapseOutput(path)
+	if err != nil {
+		return err
+	}
+	width, height := l.sim.Gameboard().Size()
+	t, err := game.NewTimelapse(width, height, every, shrink, out)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	log.Printf("recording timelapse to %s", path)
+	l.timelapse = t
+	return nil
+}
+
+// toggleTimelapse starts capturing a timelapse into a timestamped GIF, or finishes the one being captured
+func (l *simLoop) toggleTimelapse(every int, shrink int) error {
+	if l.timelapse != nil {
+		return l.stopTimelapse()
+	}
+	return l.startTimelapse(fmt.Sprintf("timelapse-%s.gif", time.Now().Format("20060102-150405")), every, shrink)
+}
+
+// stopTimelapse finishes the timelapse being captured, if there is one
+func (l *simLoop) stopTimelapse() error {
+	if l.timelapse == nil {
+		return nil
+	}
+	t := l.timelapse
+	l.timelapse = nil
+	if err := t.Close(); err != nil {
+		return err
+	}
+	log.Printf("timelapse finished with %d frames", t.Frames())
+	return nil
+}
@@ -0,0 +1,70 @@
+package window
+
+import (
+	"image"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+// drag follows the mouse while the left button is held down, so a tool can be used on every cell the cursor passes
+type drag struct {
+	// start is the cell the drag started on, last is the cell it was on the last update
+	start image.Point
+	last  image.Point
+}
+
+// update calls use with the cell under the cursor while the left button is held, along with every cell between it and
+// the last update's cell so a quick drag doesn't leave gaps. A cursor held still uses its cell again each update.
+func (d *drag) update(scale int, use func(start image.Point, at image.Point)) {
+	x, y := ebiten.CursorPosition()
+	at := image.Point{cellAt(x, scale), cellAt(y, scale)}
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		d.start, d.last = at, at
+		use(d.start, at)
+		return
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		return
+	}
+	if at == d.last {
+		use(d.start, at)
+		return
+	}
+	delta := at.Sub(d.last)
+	steps := max(abs(delta.X), abs(delta.Y))
+	for i := 1; i <= steps; i++ {
+		use(d.start, d.last.Add(image.Point{divRound(delta.X*i, steps), divRound(delta.Y*i, steps)}))
+	}
+	d.last = at
+}
+
+// cellAt returns the cell the pixel p falls in, rounding down for pixels left of or above the board
+func cellAt(p int, scale int) int {
+	if p < 0 {
+		return (p+1)/scale - 1
+	}
+	return p / scale
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// divRound divides a by b, which must be positive, rounding to the nearest whole number
+func divRound(a int, b int) int {
+	if a < 0 {
+		return -((-a + b/2) / b)
+	}
+	return (a + b/2) / b
+}
@@ -0,0 +1,122 @@
+package window
+
+import (
+	"image/color"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/text"
+	"github.com/tannerhat/Cactus-Simulator/game"
+	"golang.org/x/image/font"
+)
+
+// bufferKeepFrames is how many frames the image made from a cell buffer is kept after the buffer was last drawn. The
+// simulation hands over frames in turn, each with its own copies of the board's buffers, so a copy can go a few
+// frames without being drawn and still be wanted.
+const bufferKeepFrames = 60
+
+// Renderer is a game.Renderer that draws to an ebiten screen.
+type Renderer struct {
+	screen *ebiten.Image
+	scale  int
+	face   font.Face
+	// fills holds an image filled with each color at each size that has been drawn, so they are only made once
+	fills map[fill]*ebiten.Image
+	// buffers holds the image made from each cell buffer, they are only updated when the buffer's version changes
+	buffers map[*game.CellBuffer]*bufferImage
+	frame   int
+}
+
+type bufferImage struct {
+	image   *ebiten.Image
+	version uint64
+	// frame is the last frame the buffer was drawn in
+	frame int
+}
+
+type fill struct {
+	width  int
+	height int
+	color  color.RGBA
+}
+
+// NewRenderer returns a renderer that draws scale pixels per gameboard cell and draws text with face. SetScreen must
+// be called before drawing.
+func NewRenderer(scale int, face font.Face) *Renderer {
+	return &Renderer{
+		scale:   scale,
+		face:    face,
+		fills:   map[fill]*ebiten.Image{},
+		buffers: map[*game.CellBuffer]*bufferImage{},
+	}
+}
+
+// SetScreen changes the image being drawn to, it is called at the start of each frame.
+func (r *Renderer) SetScreen(screen *ebiten.Image) {
+	r.screen = screen
+	// let go of the images of buffers that haven't been drawn for a while, their entities are most likely gone
+	for b, cached := range r.buffers {
+		if cached.frame < r.frame-bufferKeepFrames {
+			cached.image.Dispose()
+			delete(r.buffers, b)
+		}
+	}
+	r.frame++
+}
+
+func (r *Renderer) FillCell(x int, y int, c color.Color) {
+	r.FillRect(x, y, 1, 1, c)
+}
+
+func (r *Renderer) FillRect(x int, y int, width int, height int, c color.Color) {
+	f := fill{
+		width:  width * r.scale,
+		height: height * r.scale,
+		color:  color.RGBAModel.Convert(c).(color.RGBA),
+	}
+	image, ok := r.fills[f]
+	if !ok {
+		image, _ = ebiten.NewImage(f.width, f.height, ebiten.FilterDefault)
+		image.Fill(f.color)
+		r.fills[f] = image
+	}
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(x*r.scale), float64(y*r.scale))
+	r.screen.DrawImage(image, op)
+}
+
+func (r *Renderer) DrawBuffer(b *game.CellBuffer) {
+	width, height := b.Size()
+	cached, ok := r.buffers[b]
+	if ok {
+		if w, h := cached.image.Size(); w != width || h != height {
+			cached.image.Dispose()
+			ok = false
+		}
+	}
+	if !ok {
+		image, _ := ebiten.NewImage(width, height, ebiten.FilterDefault)
+		cached = &bufferImage{image: image}
+		// a new buffer's version may well be 0, make sure its pixels are copied below
+		cached.version = b.Version() - 1
+		r.buffers[b] = cached
+	}
+	if version := b.Version(); version != cached.version {
+		cached.image.ReplacePixels(b.Image().Pix)
+		cached.version = version
+	}
+	cached.frame = r.frame
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(r.scale), float64(r.scale))
+	op.GeoM.Translate(float64(b.X*r.scale), float64(b.Y*r.scale))
+	r.screen.DrawImage(cached.image, op)
+}
+
+func (r *Renderer) DrawText(s string, x int, y int, c color.Color) {
+	text.Draw(r.screen, s, r.face, x, y, c)
+}
+
+func (r *Renderer) Scale() int {
+	return r.scale
+}
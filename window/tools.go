@@ -0,0 +1,53 @@
+package window
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/tannerhat/Cactus-Simulator/game"
+)
+
+// updateTools picks a tool with the tool keys and uses it on every cell the left mouse button is held over. Tools work
+// while the game is paused, the loop uses them between ticks.
+func (g *Game) updateTools() {
+	tools := game.Tools()
+	if len(tools) == 0 {
+		return
+	}
+	if g.bindings.JustTriggered(game.ActionNextTool) {
+		g.tool = (g.tool + 1) % len(tools)
+	}
+	if g.bindings.JustTriggered(game.ActionPrevTool) {
+		g.tool = (g.tool + len(tools) - 1) % len(tools)
+	}
+
+	var uses []game.ToolUse
+	g.toolDrag.update(g.scale, func(start image.Point, at image.Point) {
+		uses = append(uses, game.ToolUse{Tool: tools[g.tool], X: at.X, Y: at.Y})
+	})
+	if len(uses) > 0 {
+		l := g.loop
+		l.send(func() { l.useTools(uses) })
+	}
+}
+
+// drawTools draws the palette of tools along the bottom of the screen, with the selected tool in brackets
+func (g *Game) drawTools(screen *ebiten.Image) {
+	tools := game.Tools()
+	if len(tools) == 0 {
+		return
+	}
+	names := make([]string, len(tools))
+	for i, name := range tools {
+		if i == g.tool {
+			names[i] = fmt.Sprintf("[%s]", name)
+		} else {
+			names[i] = fmt.Sprintf(" %s ", name)
+		}
+	}
+	msg := fmt.Sprintf("%s/%s: %s", g.bindings.Keys(game.ActionPrevTool), g.bindings.Keys(game.ActionNextTool), strings.Join(names, ""))
+	ebitenutil.DebugPrintAt(screen, msg, 0, g.screenHeight-16)
+}